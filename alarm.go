@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	alarmCommand     = kingpin.Command("alarm", "Alarm on log patterns.")
+	alarmCreate      = alarmCommand.Command("create", "Create a metric filter counting the events matching a pattern, and an alarm on its metric.")
+	alarmGroup       = alarmCreate.Arg("group", "The log group name.").Required().HintAction(groupsCompletion).String()
+	alarmPattern     = alarmCreate.Flag("pattern", "The filter pattern of the events to count.").Required().String()
+	alarmThreshold   = alarmCreate.Flag("threshold", "Alarm when more events than the threshold match in a period.").Required().Float64()
+	alarmPeriod      = alarmCreate.Flag("period", "The period the events are counted over, a multiple of 1m.").Default("5m").Duration()
+	alarmEvaluations = alarmCreate.Flag("evaluation-periods", "How many consecutive periods must breach the threshold.").Default("1").Int64()
+	alarmSNS         = alarmCreate.Flag("sns", "The SNS topic ARN notified when the alarm fires.").String()
+	alarmName        = alarmCreate.Flag("name", "The name of the metric filter, the metric and the alarm, derived from the group and the pattern by default.").String()
+	alarmNamespace   = alarmCreate.Flag("namespace", "The metric namespace.").Default("LogMetrics").String()
+	alarmYes         = alarmCreate.Flag("yes", "Don't ask for confirmation, unless the environment is protected.").Bool()
+	alarmLs          = alarmCommand.Command("ls", "Show the state of the alarms on the metrics of the metric filters of a group.")
+	alarmLsGroup     = alarmLs.Arg("group", "The log group name.").Required().HintAction(groupsCompletion).String()
+	alarmLsOutput    = alarmLs.Flag("output", "Output format.").Short('o').Default("table").Enum("table", "json")
+)
+
+//defaultAlarmName names the alarm after the group and a hash of the pattern, e.g. cw-ecs-api-3f2a9c1e
+func defaultAlarmName(group string, pattern string) string {
+	h := fnv.New32a()
+	h.Write([]byte(pattern))
+	name := strings.Trim(regexp.MustCompile(`[^a-zA-Z0-9_.-]+`).ReplaceAllString(group, "-"), "-")
+	if len(name) > 200 {
+		name = name[:200]
+	}
+	return fmt.Sprintf("cw-%s-%08x", name, h.Sum32())
+}
+
+var comparisonSymbols = map[string]string{
+	"GreaterThanThreshold":          ">",
+	"GreaterThanOrEqualToThreshold": ">=",
+	"LessThanThreshold":             "<",
+	"LessThanOrEqualToThreshold":    "<=",
+}
+
+//formatCondition formats the alarm condition, e.g. Sum > 5 in 1 x 5m0s
+func formatCondition(a *cloudwatch.Alarm) string {
+	op, ok := comparisonSymbols[a.Comparison]
+	if !ok {
+		op = a.Comparison
+	}
+	return fmt.Sprintf("%s %s %s in %d x %s", a.Statistic, op, formatValue(a.Threshold), a.EvaluationPeriods, time.Duration(a.Period)*time.Second)
+}
+
+func formatAlarmState(state string) string {
+	switch state {
+	case "ALARM":
+		return color.RedString(state)
+	case "OK":
+		return color.GreenString(state)
+	}
+	return color.YellowString(state)
+}
+
+func alarmCreateCommand() {
+	p := *alarmPeriod
+	//the metrics of metric filters have a 1 minute resolution, shorter periods would mostly see missing data
+	if p < time.Minute || p%time.Minute != 0 {
+		kingpin.Fatalf("the period must be a multiple of 1m")
+	}
+	name := *alarmName
+	if name == "" {
+		name = defaultAlarmName(*alarmGroup, *alarmPattern)
+	}
+
+	target := cloudwatch.Target{}
+	g := newGuard("alarm", target)
+	if !g.allow("Create metric filter and alarm", fmt.Sprintf("%s on %s", name, *alarmGroup), *alarmGroup, *alarmYes) {
+		return
+	}
+
+	filter := cloudwatch.State{"pattern": *alarmPattern, "namespace": *alarmNamespace, "metric": name, "value": "1", "defaultValue": "0"}
+	err := applyChange(g, target, cloudwatch.MetricFilterChange, *alarmGroup, name, filter)
+	kingpin.FatalIfError(err, "cannot create the metric filter")
+	fmt.Printf("%s metric filter %s publishing %s/%s\n", color.GreenString("✔"), name, *alarmNamespace, name)
+
+	a := &cloudwatch.Alarm{
+		Name:              name,
+		Description:       fmt.Sprintf("Events of %s matching %s, created by cw alarm", *alarmGroup, *alarmPattern),
+		Namespace:         *alarmNamespace,
+		Metric:            name,
+		Statistic:         "Sum",
+		Comparison:        "GreaterThanThreshold",
+		Threshold:         *alarmThreshold,
+		Period:            int64(p / time.Second),
+		EvaluationPeriods: *alarmEvaluations,
+	}
+	if *alarmSNS != "" {
+		a.Actions = []string{*alarmSNS}
+	}
+	err = applyChange(g, target, cloudwatch.AlarmChange, *alarmGroup, name, cloudwatch.AlarmState(a))
+	kingpin.FatalIfError(err, "cannot create the alarm")
+	fmt.Printf("%s alarm %s: %s\n", color.GreenString("✔"), name, formatCondition(a))
+}
+
+func alarmList() {
+	target := cloudwatch.Target{}
+	filters, err := cloudwatch.MetricFilters(target, *alarmLsGroup)
+	kingpin.FatalIfError(err, "cannot describe the metric filters of %s", *alarmLsGroup)
+
+	type filterAlarm struct {
+		Filter string            `json:"filter"`
+		Alarm  *cloudwatch.Alarm `json:"alarm"`
+	}
+	var alarms []filterAlarm
+	var rows [][]string
+	for _, f := range filters {
+		metricAlarms, err := cloudwatch.AlarmsForMetric(target, f.Namespace, f.Metric)
+		kingpin.FatalIfError(err, "cannot describe the alarms of %s/%s", f.Namespace, f.Metric)
+		if len(metricAlarms) == 0 {
+			rows = append(rows, []string{f.Filter, f.Namespace + "/" + f.Metric, "", color.YellowString("no alarm"), "", ""})
+		}
+		for _, a := range metricAlarms {
+			alarms = append(alarms, filterAlarm{f.Filter, a})
+			rows = append(rows, []string{f.Filter, f.Namespace + "/" + f.Metric, a.Name, formatAlarmState(a.State), formatCondition(a), a.Updated.Local().Format(time.RFC3339)})
+		}
+	}
+	if *alarmLsOutput == "json" {
+		printJSON(alarms)
+		return
+	}
+	printTable([]string{"FILTER", "METRIC", "ALARM", "STATE", "CONDITION", "SINCE"}, rows)
+}
+
+//formatTransition renders an alarm state change as a marker line standing out of the events
+func formatTransition(tr *cloudwatch.AlarmTransition) string {
+	c := color.New(color.Bold, color.FgYellow)
+	switch tr.To {
+	case "ALARM":
+		c = color.New(color.Bold, color.FgRed)
+	case "OK":
+		c = color.New(color.Bold, color.FgGreen)
+	}
+	return c.Sprintf("━━ %s alarm %s %s → %s: %s", tr.Time.UTC().Format(time.RFC3339), tr.Alarm, tr.From, tr.To, tr.Reason)
+}
+
+//printTransition prints an alarm state change with the --template or as JSON like the events, otherwise as a marker line
+//The reason is the message of the rendered event
+func printTransition(tr *cloudwatch.AlarmTransition) {
+	e := &tailEvent{Timestamp: tr.Time.UTC(), Message: tr.Reason, Alarm: &tailAlarm{tr.Alarm, tr.From, tr.To}}
+	if s, ok := e.render(); ok {
+		fmt.Println(s)
+		return
+	}
+	fmt.Println(formatTransition(tr))
+}
+
+//tailWithAlarms prints the events interleaved with the state changes of the --with-alarms alarms
+func tailWithAlarms(events <-chan *cloudwatchlogs.FilteredLogEvent, startTime time.Time, endTime time.Time) {
+	if *follow {
+		kingpin.Fatalf("--with-alarms is not supported with --follow")
+	}
+	if endTime.IsZero() {
+		endTime = time.Now().UTC()
+	}
+	prefix := strings.TrimSuffix(*withAlarms, "*")
+	transitions, err := cloudwatch.AlarmHistory(cloudwatch.Target{}, prefix, startTime, endTime)
+	kingpin.FatalIfError(err, "cannot describe the alarm history")
+
+	mergeTransitions(events, transitions, func(event *cloudwatchlogs.FilteredLogEvent) {
+		fmt.Println(formatEvent(event))
+	}, printTransition)
+}
+
+//mergeTransitions calls onEvent and onTransition in time order, the events and the transitions being in time order
+//A transition comes before the events of the same millisecond
+func mergeTransitions(events <-chan *cloudwatchlogs.FilteredLogEvent, transitions []*cloudwatch.AlarmTransition, onEvent func(*cloudwatchlogs.FilteredLogEvent), onTransition func(*cloudwatch.AlarmTransition)) {
+	for event := range events {
+		for len(transitions) > 0 && transitions[0].Time.UnixNano()/int64(time.Millisecond) <= *event.Timestamp {
+			onTransition(transitions[0])
+			transitions = transitions[1:]
+		}
+		onEvent(event)
+	}
+	for _, tr := range transitions {
+		onTransition(tr)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultAlarmName(t *testing.T) {
+	name := defaultAlarmName("/ecs/api", `"payment failed"`)
+	assert.Regexp(t, `^cw-ecs-api-[0-9a-f]{8}$`, name)
+	assert.Equal(t, name, defaultAlarmName("/ecs/api", `"payment failed"`))
+	assert.NotEqual(t, name, defaultAlarmName("/ecs/api", "ERROR"))
+}
+
+func TestDefaultAlarmNameLongGroup(t *testing.T) {
+	name := defaultAlarmName(strings.Repeat("a", 300), "ERROR")
+	assert.Len(t, name, len("cw-")+200+len("-")+8)
+}
+
+func TestFormatCondition(t *testing.T) {
+	a := &cloudwatch.Alarm{Statistic: "Sum", Comparison: "GreaterThanThreshold", Threshold: 5, Period: 300, EvaluationPeriods: 2}
+	assert.Equal(t, "Sum > 5 in 2 x 5m0s", formatCondition(a))
+	a.Comparison = "LessThanLowerThreshold"
+	assert.Equal(t, "Sum LessThanLowerThreshold 5 in 2 x 5m0s", formatCondition(a))
+}
+
+func TestMergeTransitions(t *testing.T) {
+	at := func(ms int64) time.Time { return time.Unix(0, ms*int64(time.Millisecond)) }
+	events := make(chan *cloudwatchlogs.FilteredLogEvent, 3)
+	for i, ts := range []int64{1000, 2000, 3000} {
+		events <- &cloudwatchlogs.FilteredLogEvent{EventId: aws.String(fmt.Sprintf("e%d", i+1)), Timestamp: aws.Int64(ts)}
+	}
+	close(events)
+	transitions := []*cloudwatch.AlarmTransition{
+		{Time: at(500), Alarm: "before"},
+		//the same millisecond as the second event
+		{Time: at(2000), Alarm: "same"},
+		{Time: at(2500), Alarm: "between"},
+		{Time: at(9000), Alarm: "after"},
+	}
+
+	var merged []string
+	mergeTransitions(events, transitions, func(e *cloudwatchlogs.FilteredLogEvent) {
+		merged = append(merged, *e.EventId)
+	}, func(tr *cloudwatch.AlarmTransition) {
+		merged = append(merged, tr.Alarm)
+	})
+	assert.Equal(t, []string{"before", "e1", "same", "e2", "between", "e3", "after"}, merged)
+}
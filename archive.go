@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	archiveCommand  = kingpin.Command("archive", "Export a log group to S3, verify the export and delete the group.")
+	archiveGroup    = archiveCommand.Arg("group", "The log group name.").Required().HintAction(groupsCompletion).String()
+	archiveTo       = archiveCommand.Flag("to", "The S3 destination as s3://bucket/prefix.").Required().String()
+	archiveMinRatio = archiveCommand.Flag("min-ratio", "Minimum ratio between the exported(uncompressed) bytes and the bytes CloudWatch reports as stored.").Default("0.9").Float64()
+	archiveYes      = archiveCommand.Flag("yes", "Delete the group without asking for confirmation, unless the environment is protected.").Bool()
+)
+
+const (
+	//archiveStarting is saved before the export task is created, its ID may be lost
+	archiveStarting  = "starting"
+	archiveExporting = "exporting"
+	archiveVerified  = "verified"
+)
+
+//archiveState is persisted after every step so that an interrupted archive can be resumed
+type archiveState struct {
+	Group    string           `json:"group"`
+	Bucket   string           `json:"bucket"`
+	Prefix   string           `json:"prefix"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	TaskID   string           `json:"taskId"`
+	Stage    string           `json:"stage"`
+	Manifest *archiveManifest `json:"manifest,omitempty"`
+}
+
+type archiveManifest struct {
+	Group             *cloudwatch.LogGroup  `json:"group"`
+	TaskID            string                `json:"taskId"`
+	From              time.Time             `json:"from"`
+	To                time.Time             `json:"to"`
+	Streams           int                   `json:"streams"`
+	Objects           []cloudwatch.S3Object `json:"objects"`
+	CompressedBytes   int64                 `json:"compressedBytes"`
+	UncompressedBytes int64                 `json:"uncompressedBytes"`
+	CreatedAt         time.Time             `json:"createdAt"`
+}
+
+func archiveStatePath(group string) (string, error) {
+	return cwPath("archive", strings.Trim(strings.Replace(group, "/", "_", -1), "_")+".json")
+}
+
+func loadArchiveState(group string) (*archiveState, error) {
+	p, err := archiveStatePath(group)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s archiveState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+//remove deletes the state once the group is archived, a group recreated with the same name starts afresh
+func (s *archiveState) remove() error {
+	p, err := archiveStatePath(s.Group)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+func (s *archiveState) save() error {
+	p, err := archiveStatePath(s.Group)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p, b, 0600)
+}
+
+//archiveDestination parses the --to URL, the prefix is the one CloudWatch exports under by default when it has none
+func archiveDestination(url string) (string, string, error) {
+	bucket, prefix, err := cloudwatch.ParseS3URL(url)
+	if prefix == "" {
+		prefix = cloudwatch.DefaultExportPrefix
+	}
+	return bucket, prefix, err
+}
+
+func (s *archiveState) taskPrefix() string {
+	return path.Join(s.Prefix, s.TaskID) + "/"
+}
+
+//verifyExport checks that every stream holding events has been exported
+//and that the exported bytes are in line with the bytes CloudWatch reports
+func verifyExport(target cloudwatch.Target, s *archiveState) (*archiveManifest, error) {
+	groups, err := cloudwatch.DescribeGroups(target, s.Group)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) != 1 {
+		return nil, fmt.Errorf("log group %s not found", s.Group)
+	}
+	streams, err := cloudwatch.DescribeStreams(target, s.Group)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := cloudwatch.ListObjects(target, s.Bucket, s.taskPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	m := &archiveManifest{Group: groups[0], TaskID: s.TaskID, From: s.From, To: s.To, CreatedAt: time.Now().UTC()}
+	exported := make(map[string]bool)
+	for _, o := range objects {
+		rel := strings.TrimPrefix(o.Key, s.taskPrefix())
+		if i := strings.LastIndex(rel, "/"); i > 0 {
+			exported[rel[:i]] = true
+			m.Objects = append(m.Objects, o)
+			m.CompressedBytes += o.Size
+		}
+	}
+
+	var missing []string
+	m.Streams, missing = missingStreams(streams, exported, s.To, m.Group.RetentionInDays, time.Now())
+	if len(missing) > 0 {
+		return m, fmt.Errorf("%d streams have not been exported, e.g. %s", len(missing), missing[0])
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var sizeErr error
+	sem := make(chan struct{}, 8)
+	for _, o := range m.Objects {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			size, err := cloudwatch.GzipSize(target, s.Bucket, key)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				sizeErr = err
+			}
+			m.UncompressedBytes += size
+		}(o.Key)
+	}
+	wg.Wait()
+	if sizeErr != nil {
+		return m, sizeErr
+	}
+	if float64(m.UncompressedBytes) < float64(m.Group.StoredBytes)**archiveMinRatio {
+		return m, fmt.Errorf("exported %s but CloudWatch reports %s stored", formatBytes(m.UncompressedBytes), formatBytes(m.Group.StoredBytes))
+	}
+	return m, nil
+}
+
+//missingStreams returns how many streams hold events up to the end of the export and the ones not exported
+//The streams whose events have all expired under the group retention export nothing and are skipped
+func missingStreams(streams []*cloudwatchlogs.LogStream, exported map[string]bool, to time.Time, retentionInDays int64, now time.Time) (int, []string) {
+	var cutoff int64
+	if retentionInDays > 0 {
+		cutoff = now.AddDate(0, 0, -int(retentionInDays)).UnixNano() / int64(time.Millisecond)
+	}
+	end := to.UnixNano() / int64(time.Millisecond)
+	count := 0
+	var missing []string
+	for _, stream := range streams {
+		if stream.FirstEventTimestamp == nil || *stream.FirstEventTimestamp > end {
+			continue
+		}
+		if stream.LastEventTimestamp != nil && *stream.LastEventTimestamp < cutoff {
+			continue
+		}
+		count++
+		if !exported[*stream.LogStreamName] {
+			missing = append(missing, *stream.LogStreamName)
+		}
+	}
+	return count, missing
+}
+
+func archive() {
+	target := cloudwatch.Target{}
+	bucket, prefix, err := archiveDestination(*archiveTo)
+	kingpin.FatalIfError(err, "")
+	g := newGuard("archive", target)
+
+	s, err := loadArchiveState(*archiveGroup)
+	kingpin.FatalIfError(err, "cannot read the archive state")
+	if s != nil && (s.Bucket != bucket || s.Prefix != prefix) {
+		kingpin.Fatalf("%s is already being archived to s3://%s/%s", s.Group, s.Bucket, s.Prefix)
+	}
+	if s == nil {
+		groups, err := cloudwatch.DescribeGroups(target, *archiveGroup)
+		kingpin.FatalIfError(err, "cannot describe %s", *archiveGroup)
+		if len(groups) != 1 {
+			kingpin.Fatalf("log group %s not found", *archiveGroup)
+		}
+		s = &archiveState{Group: *archiveGroup, Bucket: bucket, Prefix: prefix,
+			From: time.Unix(0, groups[0].CreationTime*int64(time.Millisecond)).UTC(), To: time.Now().UTC()}
+	}
+
+	if s.Stage == archiveStarting {
+		s.TaskID, err = cloudwatch.FindExportTask(target, s.Group, s.Bucket, s.Prefix, s.From, s.To)
+		kingpin.FatalIfError(err, "cannot look for the export task of %s", s.Group)
+		if s.TaskID != "" {
+			s.Stage = archiveExporting
+			kingpin.FatalIfError(s.save(), "cannot save the archive state")
+		}
+	}
+	if s.TaskID == "" {
+		s.Stage = archiveStarting
+		kingpin.FatalIfError(s.save(), "cannot save the archive state")
+		s.TaskID, err = cloudwatch.ExportGroup(target, s.Group, s.Bucket, s.Prefix, s.From, s.To)
+		g.record("export", s.Group, err)
+		kingpin.FatalIfError(err, "cannot export %s (only one export task can run at a time and the bucket policy must allow logs.amazonaws.com to write)", s.Group)
+		s.Stage = archiveExporting
+		kingpin.FatalIfError(s.save(), "cannot save the archive state")
+	}
+
+	if s.Stage == archiveExporting {
+		fmt.Printf("Waiting for export task %s...\n", s.TaskID)
+		kingpin.FatalIfError(cloudwatch.WaitExportTask(target, s.TaskID, 5*time.Second), "")
+
+		m, err := verifyExport(target, s)
+		kingpin.FatalIfError(err, "export verification failed")
+		manifest, _ := json.MarshalIndent(m, "", "  ")
+		kingpin.FatalIfError(cloudwatch.PutObject(target, s.Bucket, s.taskPrefix()+"manifest.json", manifest), "cannot write the manifest")
+		s.Manifest = m
+		s.Stage = archiveVerified
+		kingpin.FatalIfError(s.save(), "cannot save the archive state")
+		fmt.Printf("%s %d streams, %d objects, %s(%s uncompressed) in s3://%s/%smanifest.json\n", color.GreenString("Exported"),
+			m.Streams, len(m.Objects), formatBytes(m.CompressedBytes), formatBytes(m.UncompressedBytes), s.Bucket, s.taskPrefix())
+	}
+
+	if s.Stage == archiveVerified {
+		if !g.allow("Delete log group", s.Group, s.Group, *archiveYes) {
+			fmt.Println("Log group not deleted, run the command again to delete it.")
+			return
+		}
+		err := applyChange(g, target, cloudwatch.GroupChange, s.Group, "", nil)
+		kingpin.FatalIfError(err, "cannot delete %s", s.Group)
+		kingpin.FatalIfError(s.remove(), "cannot remove the archive state")
+	}
+	fmt.Printf("%s %s is archived in s3://%s/%s\n", color.GreenString("Done."), s.Group, s.Bucket, s.taskPrefix())
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+)
+
+func withHome(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "cw")
+	assert.NoError(t, err)
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	return func() {
+		os.Setenv("HOME", home)
+		os.RemoveAll(dir)
+	}
+}
+
+func stream(name string, first, last time.Time) *cloudwatchlogs.LogStream {
+	ms := func(t time.Time) *int64 { return aws.Int64(t.UnixNano() / int64(time.Millisecond)) }
+	return &cloudwatchlogs.LogStream{LogStreamName: aws.String(name), FirstEventTimestamp: ms(first), LastEventTimestamp: ms(last)}
+}
+
+func TestMissingStreams(t *testing.T) {
+	now := time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)
+	streams := []*cloudwatchlogs.LogStream{
+		stream("exported", now.AddDate(0, 0, -2), now.AddDate(0, 0, -1)),
+		stream("missing", now.AddDate(0, 0, -2), now.AddDate(0, 0, -1)),
+		stream("expired", now.AddDate(0, 0, -40), now.AddDate(0, 0, -35)),
+		stream("after", now.Add(time.Hour), now.Add(2*time.Hour)),
+		{LogStreamName: aws.String("empty")},
+	}
+	exported := map[string]bool{"exported": true}
+
+	count, missing := missingStreams(streams, exported, now, 30, now)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"missing"}, missing)
+
+	count, missing = missingStreams(streams, exported, now, 0, now)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []string{"missing", "expired"}, missing)
+}
+
+func TestArchiveStateLifecycle(t *testing.T) {
+	defer withHome(t)()
+
+	s, err := loadArchiveState("/ecs/api")
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	s = &archiveState{Group: "/ecs/api", Bucket: "archive", Stage: archiveStarting}
+	assert.NoError(t, s.save())
+	loaded, err := loadArchiveState("/ecs/api")
+	assert.NoError(t, err)
+	assert.Equal(t, archiveStarting, loaded.Stage)
+
+	assert.NoError(t, s.remove())
+	loaded, err = loadArchiveState("/ecs/api")
+	assert.NoError(t, err)
+	assert.Nil(t, loaded)
+}
+
+func TestArchiveDestination(t *testing.T) {
+	bucket, prefix, err := archiveDestination("s3://archive/ecs/")
+	assert.NoError(t, err)
+	assert.Equal(t, "archive", bucket)
+	assert.Equal(t, "ecs", prefix)
+
+	bucket, prefix, err = archiveDestination("s3://archive")
+	assert.NoError(t, err)
+	assert.Equal(t, "archive", bucket)
+	assert.Equal(t, "exportedlogs", prefix)
+	s := &archiveState{Bucket: bucket, Prefix: prefix, TaskID: "42"}
+	assert.Equal(t, "exportedlogs/42/", s.taskPrefix())
+
+	_, _, err = archiveDestination("archive/ecs")
+	assert.Error(t, err)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	athenaCommand     = kingpin.Command("athena", "Query the exported logs with Athena.")
+	athenaDDL         = athenaCommand.Command("ddl", "Print the CREATE TABLE statement of the logs stored in S3.")
+	athenaLocation    = athenaDDL.Flag("location", "The S3 location of the logs as s3://bucket/prefix.").Required().String()
+	athenaFormat      = athenaDDL.Flag("format", "The format of the logs: export(an export task, e.g. cw archive), firehose(subscription records decompressed by Firehose, e.g. cw pipeline) or ndjson(a JSON message per line).").Default("export").Enum("export", "firehose", "ndjson")
+	athenaTable       = athenaDDL.Flag("table", "The table name, optionally qualified by the database.").Default("cw_logs").String()
+	athenaFields      = athenaDDL.Flag("field", "A JSON message field to map to a column, as [column=]key[:type]. Repeatable.").Strings()
+	athenaQuery       = athenaCommand.Command("query", "Run a SQL query and print its results.")
+	athenaSQL         = athenaQuery.Arg("sql", "The SQL statement, - to read it from stdin.").Required().String()
+	athenaDatabase    = athenaQuery.Flag("database", "The database the statement runs in.").Default("default").String()
+	athenaResults     = athenaQuery.Flag("results", "The S3 location Athena stores the results in as s3://bucket/prefix.").Required().String()
+	athenaQueryOutput = athenaQuery.Flag("output", "Output format.").Short('o').Default("table").Enum("table", "csv", "json")
+)
+
+//athenaField is a JSON message field mapped to a table column
+type athenaField struct {
+	column string
+	key    string
+	kind   string
+}
+
+var athenaTypes = map[string]bool{"string": true, "bigint": true, "int": true, "double": true, "boolean": true, "timestamp": true}
+
+//parseAthenaField parses a [column=]key[:type] field, the column defaults to the key in snake case
+func parseAthenaField(s string) (athenaField, error) {
+	f := athenaField{kind: "string"}
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		f.kind = strings.ToLower(s[i+1:])
+		s = s[:i]
+	}
+	if !athenaTypes[f.kind] {
+		return f, fmt.Errorf("unsupported type %q in field %q", f.kind, s)
+	}
+	if i := strings.Index(s, "="); i >= 0 {
+		f.column, f.key = s[:i], s[i+1:]
+	} else {
+		f.key = s
+		f.column = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(s), "_"), "_")
+	}
+	if f.key == "" || !regexp.MustCompile(`^[a-z_][a-z0-9_]*$`).MatchString(f.column) {
+		return f, fmt.Errorf("invalid field %q, expected [column=]key[:type] with a lowercase column", s)
+	}
+	return f, nil
+}
+
+func sqlString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+//extractField is the expression reading the field from the JSON message
+func (f athenaField) extractField(message string) string {
+	expr := fmt.Sprintf("json_extract_scalar(%s, %s)", message, sqlString("$."+f.key))
+	if f.kind != "string" {
+		expr = fmt.Sprintf("try_cast(%s AS %s)", expr, f.kind)
+	}
+	return fmt.Sprintf("%s AS %s", expr, f.column)
+}
+
+//hourlyProjection are the table properties of the Firehose prefix/YYYY/MM/DD/HH/ partitions
+//Partition projection spares loading the partitions, the last year is queryable
+func hourlyProjection(location string) string {
+	return fmt.Sprintf(`TBLPROPERTIES (
+  'projection.enabled' = 'true',
+  'projection.dt.type' = 'date',
+  'projection.dt.format' = 'yyyy/MM/dd/HH',
+  'projection.dt.range' = 'NOW-1YEARS,NOW',
+  'projection.dt.interval' = '1',
+  'projection.dt.interval.unit' = 'HOURS',
+  'storage.location.template' = %s
+)`, sqlString(location+"${dt}/"))
+}
+
+//exportDDL maps the `time message` lines of the export tasks, partitioned by task and stream
+//Export tasks aren't time partitioned, the existing partitions are listed from S3
+func exportDDL(table string, location string, fields []athenaField) (string, error) {
+	bucket, prefix, err := cloudwatch.ParseS3URL(location)
+	if err != nil {
+		return "", err
+	}
+	objects, err := cloudwatch.ListObjects(cloudwatch.Target{}, bucket, prefix)
+	if err != nil {
+		return "", err
+	}
+	type partition struct{ task, stream string }
+	seen := make(map[partition]bool)
+	var partitions []partition
+	for _, o := range objects {
+		//prefix/<task>/<stream>/000000.gz, the stream name can contain '/'
+		segments := strings.Split(strings.TrimPrefix(strings.TrimPrefix(o.Key, prefix), "/"), "/")
+		if len(segments) < 3 {
+			continue
+		}
+		p := partition{segments[0], strings.Join(segments[1:len(segments)-1], "/")}
+		if !seen[p] {
+			seen[p] = true
+			partitions = append(partitions, p)
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].task+"/"+partitions[i].stream < partitions[j].task+"/"+partitions[j].stream
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `CREATE EXTERNAL TABLE IF NOT EXISTS %s (
+  event_time string,
+  message string
+)
+PARTITIONED BY (task string, stream string)
+ROW FORMAT SERDE 'org.apache.hadoop.hive.serde2.RegexSerDe'
+WITH SERDEPROPERTIES ('input.regex' = '^(\\S+) (.*)$')
+LOCATION %s;
+`, table, sqlString(location))
+	if len(partitions) > 0 {
+		fmt.Fprintf(&b, "\nALTER TABLE %s ADD IF NOT EXISTS", table)
+		for _, p := range partitions {
+			fmt.Fprintf(&b, "\n  PARTITION (task = %s, stream = %s) LOCATION %s", sqlString(p.task), sqlString(p.stream), sqlString(location+p.task+"/"+p.stream+"/"))
+		}
+		b.WriteString(";\n")
+	}
+	if len(fields) > 0 {
+		columns := []string{"from_iso8601_timestamp(event_time) AS event_time", "message"}
+		for _, f := range fields {
+			columns = append(columns, f.extractField("message"))
+		}
+		columns = append(columns, "task", "stream")
+		fmt.Fprintf(&b, "\nCREATE OR REPLACE VIEW %s_parsed AS\nSELECT\n  %s\nFROM %s;\n", table, strings.Join(columns, ",\n  "), table)
+	}
+	return b.String(), nil
+}
+
+//firehoseDDL maps the subscription records delivered by cw pipeline, decompressed by Firehose one per line in .gz objects
+//The records stored gzipped as CloudWatch Logs sends them, concatenated without delimiters, can't be read by Athena
+func firehoseDDL(table string, location string, fields []athenaField) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `CREATE EXTERNAL TABLE IF NOT EXISTS %s (
+  messagetype string,
+  owner string,
+  loggroup string,
+  logstream string,
+  subscriptionfilters array<string>,
+  logevents array<struct<id:string,timestamp:bigint,message:string>>
+)
+PARTITIONED BY (dt string)
+ROW FORMAT SERDE 'org.openx.data.jsonserde.JsonSerDe'
+WITH SERDEPROPERTIES ('ignore.malformed.json' = 'true')
+LOCATION %s
+%s;
+`, table, sqlString(location), hourlyProjection(location))
+	columns := []string{"loggroup", "logstream", "from_unixtime(e.timestamp / 1000e0) AS event_time", "e.message AS message"}
+	for _, f := range fields {
+		columns = append(columns, f.extractField("e.message"))
+	}
+	columns = append(columns, "dt")
+	fmt.Fprintf(&b, "\nCREATE OR REPLACE VIEW %s_events AS\nSELECT\n  %s\nFROM %s\nCROSS JOIN UNNEST(logevents) AS x(e)\nWHERE messagetype = 'DATA_MESSAGE';\n", table, strings.Join(columns, ",\n  "), table)
+	return b.String()
+}
+
+//ndjsonDDL maps the fields of the JSON messages stored one per line to columns
+func ndjsonDDL(table string, location string, fields []athenaField) (string, error) {
+	if len(fields) == 0 {
+		return "", fmt.Errorf("the ndjson format needs at least a --field")
+	}
+	var columns []string
+	properties := []string{"'ignore.malformed.json' = 'true'"}
+	for _, f := range fields {
+		columns = append(columns, fmt.Sprintf("`%s` %s", f.column, f.kind))
+		if f.column != f.key {
+			properties = append(properties, fmt.Sprintf("'mapping.%s' = %s", f.column, sqlString(f.key)))
+		}
+	}
+	return fmt.Sprintf(`CREATE EXTERNAL TABLE IF NOT EXISTS %s (
+  %s
+)
+PARTITIONED BY (dt string)
+ROW FORMAT SERDE 'org.openx.data.jsonserde.JsonSerDe'
+WITH SERDEPROPERTIES (
+  %s
+)
+LOCATION %s
+%s;
+`, table, strings.Join(columns, ",\n  "), strings.Join(properties, ",\n  "), sqlString(location), hourlyProjection(location)), nil
+}
+
+func athenaDDLStatement() {
+	var fields []athenaField
+	for _, s := range *athenaFields {
+		f, err := parseAthenaField(s)
+		kingpin.FatalIfError(err, "")
+		fields = append(fields, f)
+	}
+	location := strings.TrimSuffix(*athenaLocation, "/") + "/"
+	_, _, err := cloudwatch.ParseS3URL(location)
+	kingpin.FatalIfError(err, "")
+
+	var ddl string
+	switch *athenaFormat {
+	case "export":
+		ddl, err = exportDDL(*athenaTable, location, fields)
+	case "firehose":
+		ddl = firehoseDDL(*athenaTable, location, fields)
+	case "ndjson":
+		ddl, err = ndjsonDDL(*athenaTable, location, fields)
+	}
+	kingpin.FatalIfError(err, "cannot generate the table")
+	fmt.Print(ddl)
+}
+
+func athenaRunQuery() {
+	sql := *athenaSQL
+	if sql == "-" {
+		b, err := ioutil.ReadAll(os.Stdin)
+		kingpin.FatalIfError(err, "cannot read the statement")
+		sql = string(b)
+	}
+	result, err := cloudwatch.RunQuery(cloudwatch.Target{}, sql, *athenaDatabase, *athenaResults, 2*time.Second)
+	kingpin.FatalIfError(err, "")
+
+	switch *athenaQueryOutput {
+	case "json":
+		rows := make([]map[string]string, 0, len(result.Rows))
+		for _, r := range result.Rows {
+			row := make(map[string]string)
+			for i, v := range r {
+				if i < len(result.Columns) {
+					row[result.Columns[i]] = v
+				}
+			}
+			rows = append(rows, row)
+		}
+		printJSON(rows)
+	case "csv":
+		printCSV(result.Columns, result.Rows)
+	default:
+		printTable(result.Columns, result.Rows)
+	}
+}
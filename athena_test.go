@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAthenaField(t *testing.T) {
+	f, err := parseAthenaField("latency=latencyMs:double")
+	assert.NoError(t, err)
+	assert.Equal(t, athenaField{column: "latency", key: "latencyMs", kind: "double"}, f)
+
+	f, err = parseAthenaField("level")
+	assert.NoError(t, err)
+	assert.Equal(t, "string", f.kind)
+	assert.Equal(t, "level", f.column)
+
+	_, err = parseAthenaField("latency:float")
+	assert.Error(t, err)
+}
+
+func TestExtractField(t *testing.T) {
+	f := athenaField{column: "latency", key: "latencyMs", kind: "double"}
+	assert.Equal(t, "try_cast(json_extract_scalar(e.message, '$.latencyMs') AS double) AS latency", f.extractField("e.message"))
+	assert.Equal(t, "'it''s'", sqlString("it's"))
+}
+
+func TestFirehoseDDL(t *testing.T) {
+	ddl := firehoseDDL("cw_logs", "s3://archive/firehose/", []athenaField{{column: "level", key: "level", kind: "string"}})
+	assert.Contains(t, ddl, "ROW FORMAT SERDE 'org.openx.data.jsonserde.JsonSerDe'")
+	assert.Contains(t, ddl, "LOCATION 's3://archive/firehose/'")
+	assert.Contains(t, ddl, "'storage.location.template' = 's3://archive/firehose/${dt}/'")
+	assert.Contains(t, ddl, "CROSS JOIN UNNEST(logevents) AS x(e)\nWHERE messagetype = 'DATA_MESSAGE'")
+	assert.True(t, strings.Contains(ddl, "json_extract_scalar(e.message, '$.level') AS level"))
+}
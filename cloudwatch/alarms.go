@@ -0,0 +1,220 @@
+package cloudwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	cwmetrics "github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+//Alarm is a CloudWatch alarm on a metric
+type Alarm struct {
+	Name              string    `json:"name"`
+	Description       string    `json:"description,omitempty"`
+	Namespace         string    `json:"namespace"`
+	Metric            string    `json:"metric"`
+	Statistic         string    `json:"statistic"`
+	Comparison        string    `json:"comparison"`
+	Threshold         float64   `json:"threshold"`
+	Period            int64     `json:"period"`
+	EvaluationPeriods int64     `json:"evaluationPeriods"`
+	Actions           []string  `json:"actions,omitempty"`
+	State             string    `json:"state,omitempty"`
+	Reason            string    `json:"reason,omitempty"`
+	Updated           time.Time `json:"updated,omitempty"`
+}
+
+func metricsClientFor(t Target) (*cwmetrics.CloudWatch, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return nil, err
+	}
+	return cwmetrics.New(sess), nil
+}
+
+//PutAlarm creates or updates the alarm
+//The periods without datapoints are not breaching, as metric filters publish nothing when no events are ingested
+func PutAlarm(t Target, a *Alarm) error {
+	client, err := metricsClientFor(t)
+	if err != nil {
+		return err
+	}
+	params := &cwmetrics.PutMetricAlarmInput{
+		AlarmName:          aws.String(a.Name),
+		AlarmDescription:   aws.String(a.Description),
+		Namespace:          aws.String(a.Namespace),
+		MetricName:         aws.String(a.Metric),
+		Statistic:          aws.String(a.Statistic),
+		ComparisonOperator: aws.String(a.Comparison),
+		Threshold:          aws.Float64(a.Threshold),
+		Period:             aws.Int64(a.Period),
+		EvaluationPeriods:  aws.Int64(a.EvaluationPeriods),
+		TreatMissingData:   aws.String("notBreaching"),
+	}
+	if len(a.Actions) > 0 {
+		params.AlarmActions = aws.StringSlice(a.Actions)
+	}
+	_, err = client.PutMetricAlarm(params)
+	return err
+}
+
+//AlarmsForMetric returns the alarms on the metric with their state
+func AlarmsForMetric(t Target, namespace string, metric string) ([]*Alarm, error) {
+	client, err := metricsClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.DescribeAlarmsForMetric(&cwmetrics.DescribeAlarmsForMetricInput{Namespace: aws.String(namespace), MetricName: aws.String(metric)})
+	if err != nil {
+		return nil, err
+	}
+	var alarms []*Alarm
+	for _, m := range res.MetricAlarms {
+		alarms = append(alarms, newAlarm(m))
+	}
+	return alarms, nil
+}
+
+func newAlarm(m *cwmetrics.MetricAlarm) *Alarm {
+	statistic := aws.StringValue(m.Statistic)
+	if m.ExtendedStatistic != nil {
+		statistic = *m.ExtendedStatistic
+	}
+	return &Alarm{
+		Name:              aws.StringValue(m.AlarmName),
+		Description:       aws.StringValue(m.AlarmDescription),
+		Namespace:         aws.StringValue(m.Namespace),
+		Metric:            aws.StringValue(m.MetricName),
+		Statistic:         statistic,
+		Comparison:        aws.StringValue(m.ComparisonOperator),
+		Threshold:         aws.Float64Value(m.Threshold),
+		Period:            aws.Int64Value(m.Period),
+		EvaluationPeriods: aws.Int64Value(m.EvaluationPeriods),
+		Actions:           aws.StringValueSlice(m.AlarmActions),
+		State:             aws.StringValue(m.StateValue),
+		Reason:            aws.StringValue(m.StateReason),
+		Updated:           aws.TimeValue(m.StateUpdatedTimestamp),
+	}
+}
+
+//AlarmState is the configuration of the alarm as recorded in the journal
+func AlarmState(a *Alarm) State {
+	s := State{"description": a.Description, "namespace": a.Namespace, "metric": a.Metric, "statistic": a.Statistic,
+		"comparison": a.Comparison, "threshold": strconv.FormatFloat(a.Threshold, 'f', -1, 64),
+		"period": strconv.FormatInt(a.Period, 10), "evaluationPeriods": strconv.FormatInt(a.EvaluationPeriods, 10)}
+	if len(a.Actions) > 0 {
+		s["actions"] = strings.Join(a.Actions, ",")
+	}
+	return s
+}
+
+func alarmFromState(name string, s State) (*Alarm, error) {
+	a := &Alarm{Name: name, Description: s["description"], Namespace: s["namespace"], Metric: s["metric"], Statistic: s["statistic"], Comparison: s["comparison"]}
+	var err error
+	if a.Threshold, err = strconv.ParseFloat(s["threshold"], 64); err != nil {
+		return nil, fmt.Errorf("invalid threshold %q", s["threshold"])
+	}
+	if a.Period, err = strconv.ParseInt(s["period"], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid period %q", s["period"])
+	}
+	if a.EvaluationPeriods, err = strconv.ParseInt(s["evaluationPeriods"], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid evaluation periods %q", s["evaluationPeriods"])
+	}
+	if s["actions"] != "" {
+		a.Actions = strings.Split(s["actions"], ",")
+	}
+	return a, nil
+}
+
+func alarmCurrentState(t Target, name string) (State, error) {
+	client, err := metricsClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.DescribeAlarms(&cwmetrics.DescribeAlarmsInput{AlarmNames: []*string{aws.String(name)}})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range res.MetricAlarms {
+		if aws.StringValue(m.AlarmName) == name {
+			return AlarmState(newAlarm(m)), nil
+		}
+	}
+	return nil, nil
+}
+
+func applyAlarm(t Target, name string, after State) error {
+	if after == nil {
+		client, err := metricsClientFor(t)
+		if err != nil {
+			return err
+		}
+		_, err = client.DeleteAlarms(&cwmetrics.DeleteAlarmsInput{AlarmNames: []*string{aws.String(name)}})
+		return err
+	}
+	a, err := alarmFromState(name, after)
+	if err != nil {
+		return err
+	}
+	return PutAlarm(t, a)
+}
+
+//AlarmTransition is a state change of an alarm
+type AlarmTransition struct {
+	Time   time.Time
+	Alarm  string
+	From   string
+	To     string
+	Reason string
+}
+
+//newAlarmTransition decodes the states of a state update history item, its summary is the reason when they can't be decoded
+func newAlarmTransition(item *cwmetrics.AlarmHistoryItem) *AlarmTransition {
+	tr := &AlarmTransition{Time: aws.TimeValue(item.Timestamp), Alarm: aws.StringValue(item.AlarmName), Reason: aws.StringValue(item.HistorySummary)}
+	var data struct {
+		OldState struct {
+			StateValue string `json:"stateValue"`
+		} `json:"oldState"`
+		NewState struct {
+			StateValue  string `json:"stateValue"`
+			StateReason string `json:"stateReason"`
+		} `json:"newState"`
+	}
+	if json.Unmarshal([]byte(aws.StringValue(item.HistoryData)), &data) == nil {
+		tr.From, tr.To = data.OldState.StateValue, data.NewState.StateValue
+		if data.NewState.StateReason != "" {
+			tr.Reason = data.NewState.StateReason
+		}
+	}
+	return tr
+}
+
+//AlarmHistory returns the state changes between start and end of the alarms whose name starts with prefix, in time order
+func AlarmHistory(t Target, prefix string, start time.Time, end time.Time) ([]*AlarmTransition, error) {
+	client, err := metricsClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	params := &cwmetrics.DescribeAlarmHistoryInput{
+		HistoryItemType: aws.String(cwmetrics.HistoryItemTypeStateUpdate),
+		StartDate:       aws.Time(start),
+		EndDate:         aws.Time(end),
+	}
+	var transitions []*AlarmTransition
+	err = client.DescribeAlarmHistoryPages(params, func(res *cwmetrics.DescribeAlarmHistoryOutput, lastPage bool) bool {
+		for _, item := range res.AlarmHistoryItems {
+			if !strings.HasPrefix(aws.StringValue(item.AlarmName), prefix) {
+				continue
+			}
+			transitions = append(transitions, newAlarmTransition(item))
+		}
+		return !lastPage
+	})
+	sort.SliceStable(transitions, func(i, j int) bool { return transitions[i].Time.Before(transitions[j].Time) })
+	return transitions, err
+}
@@ -0,0 +1,59 @@
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	cwmetrics "github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlarmStateRoundTrip(t *testing.T) {
+	a := &Alarm{Name: "cw-api-3f2a9c1e", Description: "errors", Namespace: "LogMetrics", Metric: "cw-api-3f2a9c1e", Statistic: "Sum",
+		Comparison: "GreaterThanThreshold", Threshold: 2.5, Period: 300, EvaluationPeriods: 2, Actions: []string{"arn:aws:sns:eu-west-1:123456789012:a", "arn:aws:sns:eu-west-1:123456789012:b"}}
+	s := AlarmState(a)
+	assert.Equal(t, "2.5", s["threshold"])
+	assert.Equal(t, "300", s["period"])
+	back, err := alarmFromState(a.Name, s)
+	assert.NoError(t, err)
+	assert.Equal(t, a, back)
+}
+
+func TestAlarmStateWithoutActions(t *testing.T) {
+	s := AlarmState(&Alarm{Name: "a", Statistic: "Sum", Threshold: 1, Period: 60, EvaluationPeriods: 1})
+	_, ok := s["actions"]
+	assert.False(t, ok)
+	back, err := alarmFromState("a", s)
+	assert.NoError(t, err)
+	assert.Nil(t, back.Actions)
+}
+
+func TestAlarmFromInvalidState(t *testing.T) {
+	_, err := alarmFromState("a", State{"threshold": "x", "period": "60", "evaluationPeriods": "1"})
+	assert.EqualError(t, err, `invalid threshold "x"`)
+	_, err = alarmFromState("a", State{"threshold": "1", "period": "", "evaluationPeriods": "1"})
+	assert.EqualError(t, err, `invalid period ""`)
+}
+
+func TestNewAlarmTransition(t *testing.T) {
+	at := time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+	item := &cwmetrics.AlarmHistoryItem{
+		AlarmName:       aws.String("cw-api"),
+		Timestamp:       aws.Time(at),
+		HistoryItemType: aws.String(cwmetrics.HistoryItemTypeStateUpdate),
+		HistorySummary:  aws.String("Alarm updated from OK to ALARM"),
+		HistoryData: aws.String(`{"version":"1.0","oldState":{"stateValue":"OK","stateReason":"Threshold Crossed: no datapoints were received"},` +
+			`"newState":{"stateValue":"ALARM","stateReason":"Threshold Crossed: 1 datapoint [7.0 (01/07/18 09:55:00)] was greater than the threshold (5.0).","stateReasonData":{"version":"1.0","threshold":5.0}}}`),
+	}
+	assert.Equal(t, &AlarmTransition{Time: at, Alarm: "cw-api", From: "OK", To: "ALARM",
+		Reason: "Threshold Crossed: 1 datapoint [7.0 (01/07/18 09:55:00)] was greater than the threshold (5.0)."}, newAlarmTransition(item))
+}
+
+func TestNewAlarmTransitionWithoutData(t *testing.T) {
+	item := &cwmetrics.AlarmHistoryItem{AlarmName: aws.String("cw-api"), HistorySummary: aws.String("Alarm updated from OK to ALARM"), HistoryData: aws.String("not json")}
+	tr := newAlarmTransition(item)
+	assert.Equal(t, "Alarm updated from OK to ALARM", tr.Reason)
+	assert.Empty(t, tr.From)
+	assert.Empty(t, tr.To)
+}
@@ -0,0 +1,93 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+//QueryResult is the result set of an Athena query
+type QueryResult struct {
+	Columns []string
+	Rows    [][]string
+}
+
+//RunQuery runs the SQL statement in the Athena database and waits for its results
+//The results are also stored by Athena in the outputLocation S3 URL
+func RunQuery(target Target, query string, database string, outputLocation string, poll time.Duration) (*QueryResult, error) {
+	sess, err := newSession(target)
+	if err != nil {
+		return nil, err
+	}
+	client := athena.New(sess)
+	params := &athena.StartQueryExecutionInput{
+		QueryString:         aws.String(query),
+		ResultConfiguration: &athena.ResultConfiguration{OutputLocation: aws.String(outputLocation)},
+	}
+	if database != "" {
+		params.QueryExecutionContext = &athena.QueryExecutionContext{Database: aws.String(database)}
+	}
+	res, err := client.StartQueryExecution(params)
+	if err != nil {
+		return nil, err
+	}
+	id := res.QueryExecutionId
+
+	for {
+		out, err := client.GetQueryExecution(&athena.GetQueryExecutionInput{QueryExecutionId: id})
+		if err != nil {
+			return nil, err
+		}
+		status := out.QueryExecution.Status
+		state := aws.StringValue(status.State)
+		if state == athena.QueryExecutionStateSucceeded {
+			break
+		}
+		if state == athena.QueryExecutionStateFailed || state == athena.QueryExecutionStateCancelled {
+			return nil, fmt.Errorf("query %s %s: %s", aws.StringValue(id), state, aws.StringValue(status.StateChangeReason))
+		}
+		time.Sleep(poll)
+	}
+
+	result := &QueryResult{}
+	first := true
+	err = client.GetQueryResultsPages(&athena.GetQueryResultsInput{QueryExecutionId: id}, func(out *athena.GetQueryResultsOutput, lastPage bool) bool {
+		rows := out.ResultSet.Rows
+		if first {
+			first = false
+			for _, c := range out.ResultSet.ResultSetMetadata.ColumnInfo {
+				result.Columns = append(result.Columns, aws.StringValue(c.Name))
+			}
+			//the first row of a SELECT repeats the column names
+			if len(rows) > 0 && isHeader(rows[0], result.Columns) {
+				rows = rows[1:]
+			}
+		}
+		for _, r := range rows {
+			row := make([]string, len(r.Data))
+			for i, d := range r.Data {
+				row[i] = aws.StringValue(d.VarCharValue)
+			}
+			result.Rows = append(result.Rows, row)
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func isHeader(row *athena.Row, columns []string) bool {
+	if len(row.Data) != len(columns) {
+		return false
+	}
+	for i, d := range row.Data {
+		if aws.StringValue(d.VarCharValue) != columns[i] {
+			return false
+		}
+	}
+	return true
+}
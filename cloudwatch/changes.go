@@ -0,0 +1,259 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+//Kinds of configuration changes
+const (
+	RetentionChange          = "retention"
+	TagChange                = "tag"
+	KmsChange                = "kms"
+	MetricFilterChange       = "metric-filter"
+	SubscriptionFilterChange = "subscription-filter"
+	GroupChange              = "group"
+	AlarmChange              = "alarm"
+)
+
+//State is the configuration of a log group setting, nil when the setting is absent
+//
+//	retention: days
+//	tag: value
+//	kms: keyId
+//	metric-filter: pattern, namespace, metric, value, defaultValue
+//	subscription-filter: pattern, destination, roleArn, distribution
+//	group: exists
+//	alarm: description, namespace, metric, statistic, comparison, threshold, period, evaluationPeriods, actions
+type State map[string]string
+
+func (s State) String() string {
+	if s == nil {
+		return "none"
+	}
+	var fields []string
+	for k, v := range s {
+		fields = append(fields, k+"="+v)
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, " ")
+}
+
+//Change is a configuration change of a log group setting
+//Name identifies the tag, filter or alarm the change is about
+type Change struct {
+	Kind   string `json:"kind"`
+	Group  string `json:"group"`
+	Name   string `json:"name,omitempty"`
+	Before State  `json:"before"`
+	After  State  `json:"after"`
+}
+
+func (c *Change) String() string {
+	setting := c.Kind
+	if c.Name != "" {
+		setting = fmt.Sprintf("%s %s", c.Kind, c.Name)
+	}
+	return fmt.Sprintf("%s of %s: %s -> %s", setting, c.Group, c.Before, c.After)
+}
+
+//IsDeletion reports whether the change creates or deletes a log group
+//Undoing it would delete data, or would need data that doesn't exist anymore
+func (c *Change) IsDeletion() bool {
+	return c.Kind == GroupChange
+}
+
+//CurrentState returns the current state of the setting
+func CurrentState(t Target, kind string, group string, name string) (State, error) {
+	if kind == AlarmChange {
+		return alarmCurrentState(t, name)
+	}
+	cwl, err := cwClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case RetentionChange, KmsChange, GroupChange:
+		res, err := cwl.DescribeLogGroups(&cloudwatchlogs.DescribeLogGroupsInput{LogGroupNamePrefix: aws.String(group)})
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range res.LogGroups {
+			if *g.LogGroupName != group {
+				continue
+			}
+			switch {
+			case kind == GroupChange:
+				return State{"exists": "true"}, nil
+			case kind == RetentionChange && g.RetentionInDays != nil:
+				return State{"days": strconv.FormatInt(*g.RetentionInDays, 10)}, nil
+			case kind == KmsChange && g.KmsKeyId != nil:
+				return State{"keyId": *g.KmsKeyId}, nil
+			}
+			return nil, nil
+		}
+		if kind == GroupChange {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("log group %s not found", group)
+	case TagChange:
+		res, err := cwl.ListTagsLogGroup(&cloudwatchlogs.ListTagsLogGroupInput{LogGroupName: aws.String(group)})
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := res.Tags[name]; ok {
+			return State{"value": aws.StringValue(v)}, nil
+		}
+		return nil, nil
+	case MetricFilterChange:
+		res, err := cwl.DescribeMetricFilters(&cloudwatchlogs.DescribeMetricFiltersInput{LogGroupName: aws.String(group), FilterNamePrefix: aws.String(name)})
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range res.MetricFilters {
+			if *f.FilterName != name || len(f.MetricTransformations) == 0 {
+				continue
+			}
+			m := f.MetricTransformations[0]
+			s := State{"pattern": aws.StringValue(f.FilterPattern), "namespace": aws.StringValue(m.MetricNamespace),
+				"metric": aws.StringValue(m.MetricName), "value": aws.StringValue(m.MetricValue)}
+			if m.DefaultValue != nil {
+				s["defaultValue"] = strconv.FormatFloat(*m.DefaultValue, 'f', -1, 64)
+			}
+			return s, nil
+		}
+		return nil, nil
+	case SubscriptionFilterChange:
+		res, err := cwl.DescribeSubscriptionFilters(&cloudwatchlogs.DescribeSubscriptionFiltersInput{LogGroupName: aws.String(group), FilterNamePrefix: aws.String(name)})
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range res.SubscriptionFilters {
+			if *f.FilterName != name {
+				continue
+			}
+			s := State{"pattern": aws.StringValue(f.FilterPattern), "destination": aws.StringValue(f.DestinationArn)}
+			if f.RoleArn != nil {
+				s["roleArn"] = *f.RoleArn
+			}
+			if f.Distribution != nil {
+				s["distribution"] = *f.Distribution
+			}
+			return s, nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown configuration %s", kind)
+}
+
+//ApplyChange sets the setting to the given state
+//It returns the change, with the state before it, to be recorded in the journal
+func ApplyChange(t Target, kind string, group string, name string, after State) (*Change, error) {
+	before, err := CurrentState(t, kind, group, name)
+	if err != nil {
+		return nil, err
+	}
+	c := &Change{Kind: kind, Group: group, Name: name, Before: before, After: after}
+	if reflect.DeepEqual(before, after) {
+		return c, nil
+	}
+	return c, apply(t, c)
+}
+
+//ApplyChangeRetrying is ApplyChange retrying the change while retryable(err), every interval until the deadline
+func ApplyChangeRetrying(t Target, kind string, group string, name string, after State, retryable func(error) bool, interval time.Duration, deadline time.Time) (*Change, error) {
+	c, err := ApplyChange(t, kind, group, name, after)
+	for c != nil && err != nil && retryable(err) && time.Now().Add(interval).Before(deadline) {
+		time.Sleep(interval)
+		err = apply(t, c)
+	}
+	return c, err
+}
+
+func apply(t Target, c *Change) error {
+	if c.Kind == AlarmChange {
+		return applyAlarm(t, c.Name, c.After)
+	}
+	cwl, err := cwClientFor(t)
+	if err != nil {
+		return err
+	}
+	group := aws.String(c.Group)
+	after := c.After
+	switch c.Kind {
+	case RetentionChange:
+		if after == nil {
+			_, err = cwl.DeleteRetentionPolicy(&cloudwatchlogs.DeleteRetentionPolicyInput{LogGroupName: group})
+			return err
+		}
+		days, err := strconv.ParseInt(after["days"], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid retention %q", after["days"])
+		}
+		_, err = cwl.PutRetentionPolicy(&cloudwatchlogs.PutRetentionPolicyInput{LogGroupName: group, RetentionInDays: aws.Int64(days)})
+		return err
+	case TagChange:
+		if after == nil {
+			_, err = cwl.UntagLogGroup(&cloudwatchlogs.UntagLogGroupInput{LogGroupName: group, Tags: []*string{aws.String(c.Name)}})
+			return err
+		}
+		_, err = cwl.TagLogGroup(&cloudwatchlogs.TagLogGroupInput{LogGroupName: group, Tags: map[string]*string{c.Name: aws.String(after["value"])}})
+		return err
+	case KmsChange:
+		if after == nil {
+			_, err = cwl.DisassociateKmsKey(&cloudwatchlogs.DisassociateKmsKeyInput{LogGroupName: group})
+			return err
+		}
+		_, err = cwl.AssociateKmsKey(&cloudwatchlogs.AssociateKmsKeyInput{LogGroupName: group, KmsKeyId: aws.String(after["keyId"])})
+		return err
+	case MetricFilterChange:
+		if after == nil {
+			_, err = cwl.DeleteMetricFilter(&cloudwatchlogs.DeleteMetricFilterInput{LogGroupName: group, FilterName: aws.String(c.Name)})
+			return err
+		}
+		m := &cloudwatchlogs.MetricTransformation{
+			MetricNamespace: aws.String(after["namespace"]),
+			MetricName:      aws.String(after["metric"]),
+			MetricValue:     aws.String(after["value"]),
+		}
+		if v, ok := after["defaultValue"]; ok {
+			d, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("invalid default value %q", v)
+			}
+			m.DefaultValue = aws.Float64(d)
+		}
+		_, err = cwl.PutMetricFilter(&cloudwatchlogs.PutMetricFilterInput{LogGroupName: group, FilterName: aws.String(c.Name),
+			FilterPattern: aws.String(after["pattern"]), MetricTransformations: []*cloudwatchlogs.MetricTransformation{m}})
+		return err
+	case SubscriptionFilterChange:
+		if after == nil {
+			_, err = cwl.DeleteSubscriptionFilter(&cloudwatchlogs.DeleteSubscriptionFilterInput{LogGroupName: group, FilterName: aws.String(c.Name)})
+			return err
+		}
+		params := &cloudwatchlogs.PutSubscriptionFilterInput{LogGroupName: group, FilterName: aws.String(c.Name),
+			FilterPattern: aws.String(after["pattern"]), DestinationArn: aws.String(after["destination"])}
+		if v, ok := after["roleArn"]; ok {
+			params.RoleArn = aws.String(v)
+		}
+		if v, ok := after["distribution"]; ok {
+			params.Distribution = aws.String(v)
+		}
+		_, err = cwl.PutSubscriptionFilter(params)
+		return err
+	case GroupChange:
+		if after == nil {
+			_, err = cwl.DeleteLogGroup(&cloudwatchlogs.DeleteLogGroupInput{LogGroupName: group})
+			return err
+		}
+		return ensureGroup(cwl, c.Group)
+	}
+	return fmt.Errorf("unknown configuration %s", c.Kind)
+}
@@ -4,6 +4,7 @@ package cloudwatch
 import (
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,15 +12,78 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 )
 
-func cwClient() *cloudwatchlogs.CloudWatchLogs {
-	sess := session.Must(session.NewSessionWithOptions(session.Options{
+//Target identifies the AWS profile and region to talk to
+//Empty fields fall back to the shared config/environment defaults
+//When RoleArn is set the role is assumed with the profile credentials
+type Target struct {
+	Profile string
+	Region  string
+	RoleArn string
+}
+
+//ParseTarget parses a target in the profile:region format
+//Either side can be omitted, e.g. "prod:" or ":eu-west-1"
+func ParseTarget(s string) Target {
+	tokens := strings.SplitN(s, ":", 2)
+	t := Target{Profile: tokens[0]}
+	if len(tokens) == 2 {
+		t.Region = tokens[1]
+	}
+	return t
+}
+
+func (t Target) String() string {
+	return fmt.Sprintf("%s:%s", t.Profile, t.Region)
+}
+
+func newSession(t Target) (*session.Session, error) {
+	opts := session.Options{
 		SharedConfigState: session.SharedConfigEnable,
-	}))
-	return cloudwatchlogs.New(sess)
+		Profile:           t.Profile,
+	}
+	if t.Region != "" {
+		opts.Config.Region = aws.String(t.Region)
+	}
+	sess, err := session.NewSessionWithOptions(opts)
+	if err != nil || t.RoleArn == "" {
+		return sess, err
+	}
+	//the assumed role credentials are shared by the sessions of all the regions
+	key := t.Profile + "|" + t.RoleArn
+	creds, ok := roleCredentials.Load(key)
+	if !ok {
+		creds, _ = roleCredentials.LoadOrStore(key, stscreds.NewCredentials(sess, t.RoleArn))
+	}
+	return sess.Copy(&aws.Config{Credentials: creds.(*credentials.Credentials)}), nil
+}
+
+var roleCredentials sync.Map
+
+//ResolveRegion returns the region the target resolves to once the shared config is applied
+func ResolveRegion(t Target) (string, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(sess.Config.Region), nil
+}
+
+func cwClient() *cloudwatchlogs.CloudWatchLogs {
+	return cloudwatchlogs.New(session.Must(newSession(Target{})))
+}
+
+func cwClientFor(t Target) (*cloudwatchlogs.CloudWatchLogs, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return nil, err
+	}
+	return cloudwatchlogs.New(sess), nil
 }
 
 func params(logGroupName string, streamNames []*string, epochStartTime int64, epochEndTime int64, grep *string, follow *bool) *cloudwatchlogs.FilterLogEventsInput {
@@ -0,0 +1,190 @@
+package cloudwatch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+//ErrNoRegion is returned when no region is configured for the target
+var ErrNoRegion = errors.New("no region configured")
+
+//Environment describes how the AWS SDK resolves a target
+type Environment struct {
+	Profile          string
+	Region           string
+	Endpoint         string
+	CredentialSource string
+	Expiry           string
+}
+
+//ResolveProfile returns the name of the shared config profile the target uses
+func ResolveProfile(t Target) string {
+	profile := t.Profile
+	for _, key := range []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE"} {
+		if profile == "" {
+			profile = os.Getenv(key)
+		}
+	}
+	if profile == "" {
+		profile = "default"
+	}
+	return profile
+}
+
+//ResolveEnvironment resolves the profile, region, endpoint and credentials of the target
+func ResolveEnvironment(t Target) (*Environment, error) {
+	env := &Environment{Profile: ResolveProfile(t)}
+
+	sess, err := newSession(t)
+	if err != nil {
+		return env, err
+	}
+	env.Region = aws.StringValue(sess.Config.Region)
+	if env.Region == "" {
+		return env, ErrNoRegion
+	}
+	env.Endpoint = sess.ClientConfig(cloudwatchlogs.EndpointsID).Endpoint
+
+	creds, err := sess.Config.Credentials.Get()
+	if err != nil {
+		return env, err
+	}
+	env.CredentialSource = creds.ProviderName
+	//this SDK doesn't expose the credentials expiration, describe what is known instead
+	switch {
+	case creds.ProviderName == stscreds.ProviderName:
+		env.Expiry = fmt.Sprintf("assumed role, renewed every %s", stscreds.DefaultDuration)
+	case creds.SessionToken != "":
+		env.Expiry = "temporary credentials, expiry unknown"
+	default:
+		env.Expiry = "long-term credentials, no expiry"
+	}
+	return env, nil
+}
+
+//Identity is the AWS identity the target credentials belong to
+type Identity struct {
+	Account string
+	Arn     string
+	UserID  string
+}
+
+//CallerIdentity returns the identity of the target credentials
+func CallerIdentity(t Target) (*Identity, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return nil, err
+	}
+	res, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Account: aws.StringValue(res.Account), Arn: aws.StringValue(res.Arn), UserID: aws.StringValue(res.UserId)}, nil
+}
+
+//principalArn returns the IAM principal of a caller ARN
+//Assumed role sessions are mapped to their role, dropping the role path that the session ARN doesn't carry
+func principalArn(callerArn string) string {
+	a, err := arn.Parse(callerArn)
+	if err != nil || a.Service != "sts" || !strings.HasPrefix(a.Resource, "assumed-role/") {
+		return callerArn
+	}
+	role := strings.Split(strings.TrimPrefix(a.Resource, "assumed-role/"), "/")[0]
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", a.Partition, a.AccountID, role)
+}
+
+//SimulatePermissions evaluates the CloudWatch Logs actions on the resource against the caller IAM policies
+//It returns the evaluation decision of each action
+func SimulatePermissions(t Target, callerArn string, actions []string, resourceArn string) (map[string]string, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return nil, err
+	}
+	params := &iam.SimulatePrincipalPolicyInput{PolicySourceArn: aws.String(principalArn(callerArn)), ResourceArns: []*string{aws.String(resourceArn)}}
+	for _, a := range actions {
+		params.ActionNames = append(params.ActionNames, aws.String("logs:"+a))
+	}
+	decisions := make(map[string]string)
+	err = iam.New(sess).SimulatePrincipalPolicyPages(params, func(res *iam.SimulatePolicyResponse, lastPage bool) bool {
+		for _, r := range res.EvaluationResults {
+			decisions[strings.TrimPrefix(aws.StringValue(r.EvalActionName), "logs:")] = aws.StringValue(r.EvalDecision)
+		}
+		return !lastPage
+	})
+	return decisions, err
+}
+
+//SampleGroup returns the name of a log group of the target, "" if there's none
+func SampleGroup(t Target) (string, error) {
+	cwl, err := cwClientFor(t)
+	if err != nil {
+		return "", err
+	}
+	res, err := cwl.DescribeLogGroups(&cloudwatchlogs.DescribeLogGroupsInput{Limit: aws.Int64(1)})
+	if err != nil || len(res.LogGroups) == 0 {
+		return "", err
+	}
+	return aws.StringValue(res.LogGroups[0].LogGroupName), nil
+}
+
+//ProbePermissions tests the read only CloudWatch Logs actions with harmless calls on the group, none when it's ""
+//It returns the outcome of each action that can be tested, a nil error meaning the action is allowed
+func ProbePermissions(t Target, actions []string, groupName string) (map[string]error, error) {
+	cwl, err := cwClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+
+	group := aws.String(groupName)
+	probes := map[string]func() error{
+		"DescribeLogGroups": func() error {
+			_, err := cwl.DescribeLogGroups(&cloudwatchlogs.DescribeLogGroupsInput{Limit: aws.Int64(1)})
+			return err
+		},
+		"DescribeExportTasks": func() error {
+			_, err := cwl.DescribeExportTasks(&cloudwatchlogs.DescribeExportTasksInput{Limit: aws.Int64(1)})
+			return err
+		},
+	}
+	if groupName != "" {
+		probes["DescribeLogStreams"] = func() error {
+			_, err := cwl.DescribeLogStreams(&cloudwatchlogs.DescribeLogStreamsInput{LogGroupName: group, Limit: aws.Int64(1)})
+			return err
+		}
+		probes["FilterLogEvents"] = func() error {
+			start := time.Now().Add(-time.Minute).UnixNano() / int64(time.Millisecond)
+			_, err := cwl.FilterLogEvents(&cloudwatchlogs.FilterLogEventsInput{LogGroupName: group, StartTime: aws.Int64(start), Limit: aws.Int64(1)})
+			return err
+		}
+		probes["ListTagsLogGroup"] = func() error {
+			_, err := cwl.ListTagsLogGroup(&cloudwatchlogs.ListTagsLogGroupInput{LogGroupName: group})
+			return err
+		}
+		probes["DescribeMetricFilters"] = func() error {
+			_, err := cwl.DescribeMetricFilters(&cloudwatchlogs.DescribeMetricFiltersInput{LogGroupName: group, Limit: aws.Int64(1)})
+			return err
+		}
+		probes["DescribeSubscriptionFilters"] = func() error {
+			_, err := cwl.DescribeSubscriptionFilters(&cloudwatchlogs.DescribeSubscriptionFiltersInput{LogGroupName: group, Limit: aws.Int64(1)})
+			return err
+		}
+	}
+
+	results := make(map[string]error)
+	for _, a := range actions {
+		if probe, ok := probes[a]; ok {
+			results[a] = probe()
+		}
+	}
+	return results, nil
+}
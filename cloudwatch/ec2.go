@@ -0,0 +1,74 @@
+package cloudwatch
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//InstanceIDPattern matches the EC2 instance IDs, e.g. in the stream names of the CloudWatch agent
+var InstanceIDPattern = regexp.MustCompile(`i-[0-9a-f]{8}(?:[0-9a-f]{9})?\b`)
+
+//Instance is an EC2 instance
+type Instance struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	AZ        string `json:"az"`
+	PrivateIP string `json:"privateIp,omitempty"`
+	Type      string `json:"type"`
+	State     string `json:"state"`
+}
+
+//InstanceResolver describes EC2 instances, caching the instances found and not found
+type InstanceResolver struct {
+	target    Target
+	mu        sync.Mutex
+	instances map[string]*Instance
+}
+
+//NewInstanceResolver returns a resolver of the instances of the target
+func NewInstanceResolver(t Target) *InstanceResolver {
+	return &InstanceResolver{target: t, instances: make(map[string]*Instance)}
+}
+
+//Resolve returns the instance, nil if it doesn't exist anymore
+func (r *InstanceResolver) Resolve(id string) (*Instance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i, ok := r.instances[id]; ok {
+		return i, nil
+	}
+	sess, err := newSession(r.target)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ec2.New(sess).DescribeInstances(&ec2.DescribeInstancesInput{InstanceIds: []*string{aws.String(id)}})
+	if isErrCode(err, "InvalidInstanceID.NotFound") || isErrCode(err, "InvalidInstanceID.Malformed") {
+		r.instances[id] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var instance *Instance
+	for _, reservation := range res.Reservations {
+		for _, i := range reservation.Instances {
+			instance = &Instance{ID: id, PrivateIP: aws.StringValue(i.PrivateIpAddress), Type: aws.StringValue(i.InstanceType)}
+			if i.Placement != nil {
+				instance.AZ = aws.StringValue(i.Placement.AvailabilityZone)
+			}
+			if i.State != nil {
+				instance.State = aws.StringValue(i.State.Name)
+			}
+			for _, t := range i.Tags {
+				if aws.StringValue(t.Key) == "Name" {
+					instance.Name = aws.StringValue(t.Value)
+				}
+			}
+		}
+	}
+	r.instances[id] = instance
+	return instance, nil
+}
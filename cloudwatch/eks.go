@@ -0,0 +1,46 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+)
+
+//EKSComponents maps the EKS control plane log types to the prefix of their streams
+var EKSComponents = map[string]string{
+	"api":               "kube-apiserver-",
+	"audit":             "kube-apiserver-audit-",
+	"authenticator":     "authenticator-",
+	"controllerManager": "kube-controller-manager-",
+	"scheduler":         "kube-scheduler-",
+}
+
+//EKSLogGroup returns the log group the control plane logs of an EKS cluster are delivered to
+func EKSLogGroup(t Target, cluster string) (string, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return "", err
+	}
+	res, err := eks.New(sess).DescribeCluster(&eks.DescribeClusterInput{Name: aws.String(cluster)})
+	if isErrCode(err, eks.ErrCodeResourceNotFoundException) {
+		return "", fmt.Errorf("no such EKS cluster: %s", cluster)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/aws/eks/%s/cluster", aws.StringValue(res.Cluster.Name)), nil
+}
+
+//EKSComponent returns the control plane log type a stream belongs to, "" if none
+func EKSComponent(stream string) string {
+	var component, prefix string
+	for c, p := range EKSComponents {
+		//the api streams prefix is a prefix of the audit ones, the longest wins
+		if strings.HasPrefix(stream, p) && len(p) > len(prefix) {
+			component, prefix = c, p
+		}
+	}
+	return component
+}
@@ -0,0 +1,16 @@
+package cloudwatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEKSComponent(t *testing.T) {
+	assert.Equal(t, "api", EKSComponent("kube-apiserver-1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d"))
+	assert.Equal(t, "audit", EKSComponent("kube-apiserver-audit-1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d"))
+	assert.Equal(t, "authenticator", EKSComponent("authenticator-1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d"))
+	assert.Equal(t, "controllerManager", EKSComponent("kube-controller-manager-1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d"))
+	assert.Equal(t, "scheduler", EKSComponent("kube-scheduler-1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d"))
+	assert.Equal(t, "", EKSComponent("cloud-controller-manager-1a2b3c4d"))
+}
@@ -0,0 +1,69 @@
+package cloudwatch
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+//Query describes a search for the events of a log group within a time window
+type Query struct {
+	Group   string
+	Start   time.Time
+	End     time.Time
+	Pattern string
+	//Limiter optionally spaces out the requests of the queries sharing it
+	Limiter *RateLimiter
+}
+
+//RateLimiter spaces out API calls to stay within a requests per second quota
+type RateLimiter struct {
+	ticker *time.Ticker
+}
+
+//NewRateLimiter returns a limiter allowing perSecond calls per second
+func NewRateLimiter(perSecond int) *RateLimiter {
+	return &RateLimiter{ticker: time.NewTicker(time.Second / time.Duration(perSecond))}
+}
+
+//Wait blocks until the next call is allowed
+func (l *RateLimiter) Wait() {
+	<-l.ticker.C
+}
+
+//Events calls fn for each event matching the query, in timestamp order, until fn returns false
+func Events(target Target, q Query, fn func(*cloudwatchlogs.FilteredLogEvent) bool) error {
+	cwl, err := cwClientFor(target)
+	if err != nil {
+		return err
+	}
+	params := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(q.Group),
+		Interleaved:  aws.Bool(true),
+		StartTime:    aws.Int64(q.Start.UnixNano() / int64(time.Millisecond)),
+		EndTime:      aws.Int64(q.End.UnixNano() / int64(time.Millisecond)),
+	}
+	if q.Pattern != "" {
+		params.FilterPattern = aws.String(q.Pattern)
+	}
+	if q.Limiter != nil {
+		//retries are rate limited as well
+		cwl.Handlers.Send.PushFront(func(*request.Request) { q.Limiter.Wait() })
+	}
+	handler := func(res *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+		for _, event := range res.Events {
+			if !fn(event) {
+				return false
+			}
+		}
+		return !lastPage
+	}
+	return cwl.FilterLogEventsPages(params, handler)
+}
+
+//EventSize returns the size of an event as metered by CloudWatch
+func EventSize(event *cloudwatchlogs.FilteredLogEvent) int64 {
+	return int64(len(aws.StringValue(event.Message))) + eventOverhead
+}
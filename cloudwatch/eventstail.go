@@ -0,0 +1,176 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+//The temporary resources of events tail are named after EventsTailPrefix
+const (
+	EventsTailPrefix   = "cw-tail-"
+	eventsTailGroups   = "/aws/events/" + EventsTailPrefix
+	eventsTailTargetID = "cw-tail"
+	eventsTailPolicy   = "cw-events-tail"
+)
+
+//EventsTail is a temporary rule delivering the matching events to a temporary log group
+type EventsTail struct {
+	Rule  string
+	Group string
+}
+
+func eventsClientFor(t Target) (*cloudwatchevents.CloudWatchEvents, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return nil, err
+	}
+	return cloudwatchevents.New(sess), nil
+}
+
+//StartEventsTail creates the log group and the rule of the default event bus sending the events matching the pattern to it
+//The description identifies the session owning the rule when sweeping
+func StartEventsTail(t Target, pattern string, description string) (*EventsTail, error) {
+	cwl, err := cwClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	ev, err := eventsClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s%d", EventsTailPrefix, time.Now().UnixNano())
+	e := &EventsTail{Rule: name, Group: "/aws/events/" + name}
+
+	if err := ensureGroup(cwl, e.Group); err != nil {
+		return nil, err
+	}
+	_, err = cwl.PutRetentionPolicy(&cloudwatchlogs.PutRetentionPolicyInput{LogGroupName: aws.String(e.Group), RetentionInDays: aws.Int64(1)})
+	if err != nil {
+		return e, err
+	}
+	groups, err := DescribeGroups(t, e.Group)
+	if err != nil || len(groups) == 0 {
+		return e, fmt.Errorf("cannot describe %s: %v", e.Group, err)
+	}
+	groupArn := strings.TrimSuffix(groups[0].Arn, ":*")
+
+	//EventBridge needs a resource policy to write to the log groups, shared by all the sessions and scoped to their groups
+	policy := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Sid":"CwEventsTail","Effect":"Allow","Principal":{"Service":"events.amazonaws.com"},"Action":["logs:CreateLogStream","logs:PutLogEvents"],"Resource":"%s*:*"}]}`,
+		strings.TrimSuffix(groupArn, name)+EventsTailPrefix)
+	_, err = cwl.PutResourcePolicy(&cloudwatchlogs.PutResourcePolicyInput{PolicyName: aws.String(eventsTailPolicy), PolicyDocument: aws.String(policy)})
+	if err != nil {
+		return e, err
+	}
+
+	_, err = ev.PutRule(&cloudwatchevents.PutRuleInput{Name: aws.String(name), EventPattern: aws.String(pattern), Description: aws.String(description),
+		State: aws.String(cloudwatchevents.RuleStateEnabled)})
+	if err != nil {
+		return e, err
+	}
+	res, err := ev.PutTargets(&cloudwatchevents.PutTargetsInput{Rule: aws.String(name),
+		Targets: []*cloudwatchevents.Target{{Id: aws.String(eventsTailTargetID), Arn: aws.String(groupArn)}}})
+	if err == nil && aws.Int64Value(res.FailedEntryCount) > 0 {
+		err = fmt.Errorf("cannot target %s: %s", e.Group, aws.StringValue(res.FailedEntries[0].ErrorMessage))
+	}
+	return e, err
+}
+
+//Stop deletes the rule and the log group, ignoring the ones already deleted
+func (e *EventsTail) Stop(t Target) error {
+	cwl, err := cwClientFor(t)
+	if err != nil {
+		return err
+	}
+	ev, err := eventsClientFor(t)
+	if err != nil {
+		return err
+	}
+	_, err = ev.RemoveTargets(&cloudwatchevents.RemoveTargetsInput{Rule: aws.String(e.Rule), Ids: []*string{aws.String(eventsTailTargetID)}})
+	if err != nil && !isErrCode(err, cloudwatchevents.ErrCodeResourceNotFoundException) {
+		return err
+	}
+	_, err = ev.DeleteRule(&cloudwatchevents.DeleteRuleInput{Name: aws.String(e.Rule)})
+	if err != nil && !isErrCode(err, cloudwatchevents.ErrCodeResourceNotFoundException) {
+		return err
+	}
+	_, err = cwl.DeleteLogGroup(&cloudwatchlogs.DeleteLogGroupInput{LogGroupName: aws.String(e.Group)})
+	if err != nil && !isErrCode(err, cloudwatchlogs.ErrCodeResourceNotFoundException) {
+		return err
+	}
+	return nil
+}
+
+//SweepEventsTails deletes the rules and log groups left behind by the sessions that are not alive anymore
+//alive is called with the description of each rule, the groups without a rule are swept after a grace period
+//The resource policy is deleted as well once no session is left
+func SweepEventsTails(t Target, alive func(description string) bool) ([]*EventsTail, error) {
+	ev, err := eventsClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	cwl, err := cwClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	var swept []*EventsTail
+	live := 0
+	rules := make(map[string]bool)
+	params := &cloudwatchevents.ListRulesInput{NamePrefix: aws.String(EventsTailPrefix)}
+	for {
+		res, err := ev.ListRules(params)
+		if err != nil {
+			return swept, err
+		}
+		for _, r := range res.Rules {
+			if alive(aws.StringValue(r.Description)) {
+				rules[*r.Name] = true
+				live++
+				continue
+			}
+			e := &EventsTail{Rule: *r.Name, Group: "/aws/events/" + *r.Name}
+			if err := e.Stop(t); err != nil {
+				return swept, err
+			}
+			swept = append(swept, e)
+		}
+		if res.NextToken == nil {
+			break
+		}
+		params.NextToken = res.NextToken
+	}
+
+	groups, err := DescribeGroups(t, eventsTailGroups+"*")
+	if err != nil {
+		return swept, err
+	}
+	//a session can be creating its rule right now
+	grace := time.Now().Add(-10*time.Minute).UnixNano() / int64(time.Millisecond)
+	for _, g := range groups {
+		name := strings.TrimPrefix(g.Name, "/aws/events/")
+		if rules[name] {
+			continue
+		}
+		if g.CreationTime > grace {
+			live++
+			continue
+		}
+		e := &EventsTail{Rule: name, Group: g.Name}
+		if err := e.Stop(t); err != nil {
+			return swept, err
+		}
+		swept = append(swept, e)
+	}
+
+	if live == 0 {
+		_, err = cwl.DeleteResourcePolicy(&cloudwatchlogs.DeleteResourcePolicyInput{PolicyName: aws.String(eventsTailPolicy)})
+		if err != nil && !isErrCode(err, cloudwatchlogs.ErrCodeResourceNotFoundException) {
+			return swept, err
+		}
+	}
+	return swept, nil
+}
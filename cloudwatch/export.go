@@ -0,0 +1,104 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+//DefaultExportPrefix is the prefix CloudWatch exports under when the task has none
+const DefaultExportPrefix = "exportedlogs"
+
+//ExportGroup starts an export task of the group events between from and to into the S3 bucket, under the prefix
+//It returns the export task ID
+func ExportGroup(target Target, groupName string, bucket string, prefix string, from time.Time, to time.Time) (string, error) {
+	cwl, err := cwClientFor(target)
+	if err != nil {
+		return "", err
+	}
+	params := &cloudwatchlogs.CreateExportTaskInput{
+		LogGroupName:      aws.String(groupName),
+		Destination:       aws.String(bucket),
+		From:              aws.Int64(from.UnixNano() / int64(time.Millisecond)),
+		To:                aws.Int64(to.UnixNano() / int64(time.Millisecond)),
+		DestinationPrefix: aws.String(prefix),
+	}
+	res, err := cwl.CreateExportTask(params)
+	if err != nil {
+		return "", err
+	}
+	return *res.TaskId, nil
+}
+
+//FindExportTask returns the ID of a pending or running export task of the group to the destination, "" if none
+//It finds the task of an export started by a process that stopped before saving its ID
+func FindExportTask(target Target, groupName string, bucket string, prefix string, from time.Time, to time.Time) (string, error) {
+	cwl, err := cwClientFor(target)
+	if err != nil {
+		return "", err
+	}
+	for _, code := range []string{cloudwatchlogs.ExportTaskStatusCodeRunning, cloudwatchlogs.ExportTaskStatusCodePending, cloudwatchlogs.ExportTaskStatusCodeCompleted} {
+		params := &cloudwatchlogs.DescribeExportTasksInput{StatusCode: aws.String(code)}
+		for {
+			res, err := cwl.DescribeExportTasks(params)
+			if err != nil {
+				return "", err
+			}
+			for _, t := range res.ExportTasks {
+				if aws.StringValue(t.LogGroupName) == groupName && aws.StringValue(t.Destination) == bucket &&
+					aws.StringValue(t.DestinationPrefix) == prefix &&
+					aws.Int64Value(t.From) == from.UnixNano()/int64(time.Millisecond) && aws.Int64Value(t.To) == to.UnixNano()/int64(time.Millisecond) {
+					return aws.StringValue(t.TaskId), nil
+				}
+			}
+			if res.NextToken == nil {
+				break
+			}
+			params.NextToken = res.NextToken
+		}
+	}
+	return "", nil
+}
+
+//WaitExportTask polls the export task until it completes
+//It returns an error if the task fails or is cancelled
+func WaitExportTask(target Target, taskID string, poll time.Duration) error {
+	cwl, err := cwClientFor(target)
+	if err != nil {
+		return err
+	}
+	for {
+		res, err := cwl.DescribeExportTasks(&cloudwatchlogs.DescribeExportTasksInput{TaskId: aws.String(taskID)})
+		if err != nil {
+			return err
+		}
+		if len(res.ExportTasks) == 0 {
+			return fmt.Errorf("export task %s not found", taskID)
+		}
+		status := res.ExportTasks[0].Status
+		switch aws.StringValue(status.Code) {
+		case cloudwatchlogs.ExportTaskStatusCodeCompleted:
+			return nil
+		case cloudwatchlogs.ExportTaskStatusCodeFailed, cloudwatchlogs.ExportTaskStatusCodeCancelled:
+			return fmt.Errorf("export task %s %s: %s", taskID, aws.StringValue(status.Code), aws.StringValue(status.Message))
+		}
+		time.Sleep(poll)
+	}
+}
+
+//DescribeStreams returns the streams of the given group
+func DescribeStreams(target Target, groupName string) ([]*cloudwatchlogs.LogStream, error) {
+	cwl, err := cwClientFor(target)
+	if err != nil {
+		return nil, err
+	}
+	var streams []*cloudwatchlogs.LogStream
+	params := &cloudwatchlogs.DescribeLogStreamsInput{LogGroupName: aws.String(groupName)}
+	err = cwl.DescribeLogStreamsPages(params, func(res *cloudwatchlogs.DescribeLogStreamsOutput, lastPage bool) bool {
+		streams = append(streams, res.LogStreams...)
+		return !lastPage
+	})
+	return streams, err
+}
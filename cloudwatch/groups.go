@@ -0,0 +1,138 @@
+package cloudwatch
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+//LogGroup holds the configuration of a log group
+//Tags and filters are only populated by DescribeGroupsConfig
+type LogGroup struct {
+	Name                string            `json:"name"`
+	Arn                 string            `json:"arn"`
+	CreationTime        int64             `json:"creationTime"`
+	RetentionInDays     int64             `json:"retentionInDays,omitempty"`
+	KmsKeyID            string            `json:"kmsKeyId,omitempty"`
+	StoredBytes         int64             `json:"storedBytes"`
+	Tags                map[string]string `json:"tags,omitempty"`
+	MetricFilters       map[string]string `json:"metricFilters,omitempty"`
+	SubscriptionFilters map[string]string `json:"subscriptionFilters,omitempty"`
+}
+
+//DescribeGroups returns the log groups matching the given pattern, sorted by name
+func DescribeGroups(target Target, pattern string) ([]*LogGroup, error) {
+	cwl, err := cwClientFor(target)
+	if err != nil {
+		return nil, err
+	}
+	params := &cloudwatchlogs.DescribeLogGroupsInput{}
+	if prefix := patternPrefix(pattern); prefix != "" {
+		params.LogGroupNamePrefix = aws.String(prefix)
+	}
+
+	var groups []*LogGroup
+	handler := func(res *cloudwatchlogs.DescribeLogGroupsOutput, lastPage bool) bool {
+		for _, g := range res.LogGroups {
+			if !MatchGroup(pattern, *g.LogGroupName) {
+				continue
+			}
+			groups = append(groups, &LogGroup{
+				Name:            *g.LogGroupName,
+				Arn:             aws.StringValue(g.Arn),
+				CreationTime:    aws.Int64Value(g.CreationTime),
+				RetentionInDays: aws.Int64Value(g.RetentionInDays),
+				KmsKeyID:        aws.StringValue(g.KmsKeyId),
+				StoredBytes:     aws.Int64Value(g.StoredBytes),
+			})
+		}
+		return !lastPage
+	}
+	if err := cwl.DescribeLogGroupsPages(params, handler); err != nil {
+		return nil, err
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+//DescribeGroupsConfig returns the log groups matching the given pattern
+//including their tags, metric filters and subscription filters
+func DescribeGroupsConfig(target Target, pattern string) ([]*LogGroup, error) {
+	groups, err := DescribeGroups(target, pattern)
+	if err != nil {
+		return nil, err
+	}
+	cwl, err := cwClientFor(target)
+	if err != nil {
+		return nil, err
+	}
+
+	//the Describe*Filters APIs are limited to 5 reqs/sec, keep the fan out small
+	sem := make(chan struct{}, 4)
+	errs := make(chan error, len(groups))
+	var wg sync.WaitGroup
+	for _, g := range groups {
+		wg.Add(1)
+		go func(g *LogGroup) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := groupConfig(cwl, g); err != nil {
+				errs <- err
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func groupConfig(cwl *cloudwatchlogs.CloudWatchLogs, g *LogGroup) error {
+	tags, err := cwl.ListTagsLogGroup(&cloudwatchlogs.ListTagsLogGroupInput{LogGroupName: aws.String(g.Name)})
+	if err != nil {
+		return err
+	}
+	g.Tags = aws.StringValueMap(tags.Tags)
+
+	g.MetricFilters = make(map[string]string)
+	mfParams := &cloudwatchlogs.DescribeMetricFiltersInput{LogGroupName: aws.String(g.Name)}
+	err = cwl.DescribeMetricFiltersPages(mfParams, func(res *cloudwatchlogs.DescribeMetricFiltersOutput, lastPage bool) bool {
+		for _, f := range res.MetricFilters {
+			desc := "pattern=" + aws.StringValue(f.FilterPattern)
+			for _, t := range f.MetricTransformations {
+				desc += " metric=" + aws.StringValue(t.MetricNamespace) + "/" + aws.StringValue(t.MetricName)
+			}
+			g.MetricFilters[*f.FilterName] = desc
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return err
+	}
+
+	g.SubscriptionFilters = make(map[string]string)
+	sfParams := &cloudwatchlogs.DescribeSubscriptionFiltersInput{LogGroupName: aws.String(g.Name)}
+	return cwl.DescribeSubscriptionFiltersPages(sfParams, func(res *cloudwatchlogs.DescribeSubscriptionFiltersOutput, lastPage bool) bool {
+		for _, f := range res.SubscriptionFilters {
+			g.SubscriptionFilters[*f.FilterName] = "pattern=" + aws.StringValue(f.FilterPattern) +
+				" destination=" + portableArn(aws.StringValue(f.DestinationArn))
+		}
+		return !lastPage
+	})
+}
+
+//portableArn strips region and account ID from an ARN
+//so that equivalent resources in different environments compare equal
+func portableArn(s string) string {
+	a, err := arn.Parse(s)
+	if err != nil {
+		return s
+	}
+	return a.Service + ":" + a.Resource
+}
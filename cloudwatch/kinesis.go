@@ -0,0 +1,171 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+)
+
+//KinesisTail describes a tail of the log events delivered to a Kinesis stream by a subscription filter
+type KinesisTail struct {
+	Stream        string
+	LogGroupName  string
+	LogStreamName string
+	StartTime     time.Time
+	//EndTime stops the tail of a shard at the first record holding events after it, zero for no end
+	//The record is neither published nor checkpointed, the next tail reads it again
+	EndTime time.Time
+	Follow  bool
+	//Checkpoints maps the shard IDs to the last sequence number read, the shards without a checkpoint are read from StartTime
+	Checkpoints map[string]string
+	//OnCheckpoint is called once the records of a shard up to the sequence number have been published
+	OnCheckpoint func(shardID string, sequenceNumber string)
+}
+
+func shardIterator(kc *kinesis.Kinesis, t *KinesisTail, shardID string) (*string, error) {
+	params := &kinesis.GetShardIteratorInput{StreamName: aws.String(t.Stream), ShardId: aws.String(shardID)}
+	if seq, ok := t.Checkpoints[shardID]; ok {
+		params.ShardIteratorType = aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber)
+		params.StartingSequenceNumber = aws.String(seq)
+	} else {
+		params.ShardIteratorType = aws.String(kinesis.ShardIteratorTypeAtTimestamp)
+		params.Timestamp = aws.Time(t.StartTime)
+	}
+	res, err := kc.GetShardIterator(params)
+	if err != nil {
+		return nil, err
+	}
+	return res.ShardIterator, nil
+}
+
+func listShards(kc *kinesis.Kinesis, stream string) ([]string, error) {
+	var shards []string
+	params := &kinesis.ListShardsInput{StreamName: aws.String(stream)}
+	for {
+		res, err := kc.ListShards(params)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range res.Shards {
+			shards = append(shards, *s.ShardId)
+		}
+		if res.NextToken == nil {
+			return shards, nil
+		}
+		params = &kinesis.ListShardsInput{NextToken: res.NextToken}
+	}
+}
+
+//recordsEvents decodes the events of the group and stream of the records
+//It stops at the first record holding events after the end time, returning how many records were read and whether it stopped
+func recordsEvents(records []*kinesis.Record, t *KinesisTail) ([]*cloudwatchlogs.FilteredLogEvent, int, bool) {
+	end := t.EndTime.UnixNano() / int64(time.Millisecond)
+	var events []*cloudwatchlogs.FilteredLogEvent
+	for i, r := range records {
+		var recordEvents []*cloudwatchlogs.FilteredLogEvent
+		err := DecodeSubscriptionMessages(bytes.NewReader(r.Data), func(m *SubscriptionMessage) error {
+			recordEvents = append(recordEvents, m.Events(t.LogGroupName, t.LogStreamName)...)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot decode record %s: %s\n", aws.StringValue(r.SequenceNumber), err)
+		}
+		if !t.EndTime.IsZero() {
+			for _, e := range recordEvents {
+				if *e.Timestamp > end {
+					return events, i, true
+				}
+			}
+		}
+		events = append(events, recordEvents...)
+	}
+	return events, len(records), false
+}
+
+//TailKinesis reads the shards of the Kinesis stream decoding the CloudWatch Logs subscription records
+//It returns a channel where the events of the given log group and stream(a prefix, '*' for all) are published
+//Unless the follow flag is true the channel is closed once all the shards have been read up to the latest record, or to the end time
+func TailKinesis(target Target, t *KinesisTail) <-chan *cloudwatchlogs.FilteredLogEvent {
+	ch := make(chan *cloudwatchlogs.FilteredLogEvent)
+	fail := func(err error) {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	sess, err := newSession(target)
+	if err != nil {
+		fail(err)
+	}
+	kc := kinesis.New(sess)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	started := make(map[string]bool)
+	var readShard func(shardID string)
+	startShards := func() {
+		shards, err := listShards(kc, t.Stream)
+		if err != nil {
+			fail(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, id := range shards {
+			if !started[id] {
+				started[id] = true
+				wg.Add(1)
+				go readShard(id)
+			}
+		}
+	}
+
+	readShard = func(shardID string) {
+		defer wg.Done()
+		iterator, err := shardIterator(kc, t, shardID)
+		if err != nil {
+			fail(err)
+		}
+		for iterator != nil {
+			res, err := kc.GetRecords(&kinesis.GetRecordsInput{ShardIterator: iterator})
+			if err != nil {
+				fail(err)
+			}
+			events, read, ended := recordsEvents(res.Records, t)
+			for _, event := range events {
+				ch <- event
+			}
+			if read > 0 && t.OnCheckpoint != nil {
+				t.OnCheckpoint(shardID, *res.Records[read-1].SequenceNumber)
+			}
+			if ended {
+				return
+			}
+			iterator = res.NextShardIterator
+			if iterator == nil {
+				//the shard has been closed by a resharding, pick up its children
+				startShards()
+				return
+			}
+			if aws.Int64Value(res.MillisBehindLatest) == 0 {
+				if !t.Follow {
+					return
+				}
+				//GetRecords accepts 5 reqs/sec per shard
+				time.Sleep(time.Second)
+			} else {
+				time.Sleep(250 * time.Millisecond)
+			}
+		}
+	}
+
+	startShards()
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
@@ -0,0 +1,64 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/stretchr/testify/assert"
+)
+
+func kinesisRecord(seq string, group string, timestamps ...int64) *kinesis.Record {
+	events := ""
+	for i, ts := range timestamps {
+		if i > 0 {
+			events += ","
+		}
+		events += fmt.Sprintf(`{"id":"%s-%d","timestamp":%d,"message":"m"}`, seq, i, ts)
+	}
+	m := fmt.Sprintf(`{"messageType":"DATA_MESSAGE","logGroup":"%s","logStream":"web/1","logEvents":[%s]}`, group, events)
+	return &kinesis.Record{SequenceNumber: aws.String(seq), Data: gzipped(m)}
+}
+
+func eventIDs(events []*cloudwatchlogs.FilteredLogEvent) []string {
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, *e.EventId)
+	}
+	return ids
+}
+
+func TestRecordsEventsWithoutEnd(t *testing.T) {
+	records := []*kinesis.Record{kinesisRecord("1", "/ecs/api", 1000), kinesisRecord("2", "/ecs/web", 5000), kinesisRecord("3", "/ecs/api", 9000)}
+	events, read, ended := recordsEvents(records, &KinesisTail{LogGroupName: "/ecs/api", LogStreamName: "*"})
+	assert.Equal(t, []string{"1-0", "3-0"}, eventIDs(events))
+	assert.Equal(t, 3, read)
+	assert.False(t, ended)
+}
+
+func TestRecordsEventsStopsAtTheEnd(t *testing.T) {
+	end := time.Unix(5, 0)
+	records := []*kinesis.Record{
+		kinesisRecord("1", "/ecs/api", 1000, 2000),
+		//the events of other groups don't stop the tail
+		kinesisRecord("2", "/ecs/web", 9000),
+		kinesisRecord("3", "/ecs/api", 5000),
+		//partly after the end, left for the next tail
+		kinesisRecord("4", "/ecs/api", 4000, 6000),
+		kinesisRecord("5", "/ecs/api", 3000),
+	}
+	events, read, ended := recordsEvents(records, &KinesisTail{LogGroupName: "/ecs/api", LogStreamName: "*", EndTime: end})
+	assert.Equal(t, []string{"1-0", "1-1", "3-0"}, eventIDs(events))
+	assert.Equal(t, 3, read)
+	assert.True(t, ended)
+}
+
+func TestRecordsEventsFirstRecordAfterTheEnd(t *testing.T) {
+	events, read, ended := recordsEvents([]*kinesis.Record{kinesisRecord("1", "/ecs/api", 6000)}, &KinesisTail{LogGroupName: "*", LogStreamName: "*", EndTime: time.Unix(5, 0)})
+	assert.Empty(t, events)
+	assert.Equal(t, 0, read)
+	assert.True(t, ended)
+}
@@ -0,0 +1,123 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	cwmetrics "github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+//MaxDatapoints is the maximum number of datapoints GetMetricStatistics returns
+const MaxDatapoints = 1440
+
+//MetricFilter is a metric transformation of a metric filter of a log group
+type MetricFilter struct {
+	Filter    string `json:"filter"`
+	Namespace string `json:"namespace"`
+	Metric    string `json:"metric"`
+	Value     string `json:"value"`
+	Pattern   string `json:"pattern"`
+}
+
+//Datapoint is the statistic of a metric over a period
+type Datapoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+	Unit  string    `json:"unit"`
+}
+
+//MetricFilters returns the metrics the metric filters of the group publish
+func MetricFilters(t Target, group string) ([]*MetricFilter, error) {
+	cwl, err := cwClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	var filters []*MetricFilter
+	params := &cloudwatchlogs.DescribeMetricFiltersInput{LogGroupName: aws.String(group)}
+	err = cwl.DescribeMetricFiltersPages(params, func(res *cloudwatchlogs.DescribeMetricFiltersOutput, lastPage bool) bool {
+		for _, f := range res.MetricFilters {
+			for _, m := range f.MetricTransformations {
+				filters = append(filters, &MetricFilter{Filter: aws.StringValue(f.FilterName), Namespace: aws.StringValue(m.MetricNamespace),
+					Metric: aws.StringValue(m.MetricName), Value: aws.StringValue(m.MetricValue), Pattern: aws.StringValue(f.FilterPattern)})
+			}
+		}
+		return !lastPage
+	})
+	return filters, err
+}
+
+//ParseMetric splits a NAMESPACE/METRIC name, the namespace can contain '/'
+func ParseMetric(s string) (string, string, error) {
+	i := strings.LastIndex(s, "/")
+	if i <= 0 || i == len(s)-1 {
+		return "", "", fmt.Errorf("invalid metric %q, expected NAMESPACE/METRIC", s)
+	}
+	return s[:i], s[i+1:], nil
+}
+
+//IsStatistic reports whether the statistic is a standard one(e.g. Sum) or a percentile(e.g. p99)
+func IsStatistic(stat string) bool {
+	for _, s := range []string{cwmetrics.StatisticSum, cwmetrics.StatisticAverage, cwmetrics.StatisticMinimum, cwmetrics.StatisticMaximum, cwmetrics.StatisticSampleCount} {
+		if s == stat {
+			return true
+		}
+	}
+	var p float64
+	_, err := fmt.Sscanf(stat, "p%g", &p)
+	return err == nil && p >= 0 && p <= 100
+}
+
+//MetricStatistics returns the statistic of the metric for each period between start and end, in time order
+//The periods without data are missing
+func MetricStatistics(t Target, namespace string, metric string, stat string, start time.Time, end time.Time, period time.Duration) ([]Datapoint, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return nil, err
+	}
+	if n := int64(end.Sub(start) / period); n > MaxDatapoints {
+		return nil, fmt.Errorf("%d periods requested, at most %d are returned: use a longer period", n, MaxDatapoints)
+	}
+	params := &cwmetrics.GetMetricStatisticsInput{
+		Namespace:  aws.String(namespace),
+		MetricName: aws.String(metric),
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(end),
+		Period:     aws.Int64(int64(period / time.Second)),
+	}
+	standard := !strings.HasPrefix(stat, "p")
+	if standard {
+		params.Statistics = []*string{aws.String(stat)}
+	} else {
+		params.ExtendedStatistics = []*string{aws.String(stat)}
+	}
+	res, err := cwmetrics.New(sess).GetMetricStatistics(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []Datapoint
+	for _, d := range res.Datapoints {
+		p := Datapoint{Time: aws.TimeValue(d.Timestamp), Unit: aws.StringValue(d.Unit)}
+		switch {
+		case !standard:
+			p.Value = aws.Float64Value(d.ExtendedStatistics[stat])
+		case stat == cwmetrics.StatisticSum:
+			p.Value = aws.Float64Value(d.Sum)
+		case stat == cwmetrics.StatisticAverage:
+			p.Value = aws.Float64Value(d.Average)
+		case stat == cwmetrics.StatisticMinimum:
+			p.Value = aws.Float64Value(d.Minimum)
+		case stat == cwmetrics.StatisticMaximum:
+			p.Value = aws.Float64Value(d.Maximum)
+		case stat == cwmetrics.StatisticSampleCount:
+			p.Value = aws.Float64Value(d.SampleCount)
+		}
+		points = append(points, p)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points, nil
+}
@@ -0,0 +1,42 @@
+package cloudwatch
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/organizations"
+)
+
+//Account is an active account of the organization
+type Account struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+//ListAccounts returns the active accounts of the organization the target belongs to
+func ListAccounts(t Target) ([]Account, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []Account
+	err = organizations.New(sess).ListAccountsPages(&organizations.ListAccountsInput{}, func(res *organizations.ListAccountsOutput, lastPage bool) bool {
+		for _, a := range res.Accounts {
+			if aws.StringValue(a.Status) == organizations.AccountStatusActive {
+				accounts = append(accounts, Account{ID: aws.StringValue(a.Id), Name: aws.StringValue(a.Name)})
+			}
+		}
+		return !lastPage
+	})
+	return accounts, err
+}
+
+//AccountTarget returns the target assuming the role in the account
+//The role isn't assumed in the account of the caller, where it usually doesn't exist
+func AccountTarget(t Target, callerAccount string, account string, role string, region string) Target {
+	at := Target{Profile: t.Profile, Region: region}
+	if account != callerAccount {
+		at.RoleArn = fmt.Sprintf("arn:aws:iam::%s:role/%s", account, role)
+	}
+	return at
+}
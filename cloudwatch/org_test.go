@@ -0,0 +1,14 @@
+package cloudwatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountTarget(t *testing.T) {
+	base := Target{Profile: "admin", Region: "eu-west-1"}
+	assert.Equal(t, Target{Profile: "admin", Region: "us-east-1"}, AccountTarget(base, "111111111111", "111111111111", "Audit", "us-east-1"))
+	assert.Equal(t, Target{Profile: "admin", Region: "us-east-1", RoleArn: "arn:aws:iam::222222222222:role/Audit"},
+		AccountTarget(base, "111111111111", "222222222222", "Audit", "us-east-1"))
+}
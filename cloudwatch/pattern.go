@@ -0,0 +1,95 @@
+package cloudwatch
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+//patternPrefix returns the literal part of a group pattern preceding the first wildcard
+//It's used as LogGroupNamePrefix to narrow down the DescribeLogGroups calls
+func patternPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, "*?"); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+var groupPatterns sync.Map
+
+//MatchGroup reports whether a log group name matches a shell-like pattern
+//'*' matches any sequence of characters, '/' included, '?' matches a single character
+//An empty pattern matches every group
+func MatchGroup(pattern string, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == name
+	}
+	if re, ok := groupPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp).MatchString(name)
+	}
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	re := regexp.MustCompile(expr.String())
+	groupPatterns.Store(pattern, re)
+	return re.MatchString(name)
+}
+
+//MatchTerms reports whether the message matches a CloudWatch Logs terms filter pattern
+//All the terms, or "quoted phrases", must appear in the message, ?term matches when any of the ?terms appears
+//and -term excludes the messages containing it. JSON and space delimited patterns are not supported
+func MatchTerms(pattern string, message string) bool {
+	var terms []string
+	for rest := strings.TrimSpace(pattern); rest != ""; rest = strings.TrimSpace(rest) {
+		prefix := ""
+		if rest[0] == '?' || rest[0] == '-' {
+			prefix, rest = rest[:1], rest[1:]
+		}
+		if strings.HasPrefix(rest, `"`) {
+			phrase := rest[1:]
+			rest = ""
+			if end := strings.Index(phrase, `"`); end >= 0 {
+				phrase, rest = phrase[:end], phrase[end+1:]
+			}
+			terms = append(terms, prefix+phrase)
+			continue
+		}
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		terms = append(terms, prefix+rest[:end])
+		rest = rest[end:]
+	}
+
+	anyOf, anyMatched := false, false
+	for _, term := range terms {
+		switch {
+		case strings.HasPrefix(term, "?"):
+			anyOf = true
+			anyMatched = anyMatched || strings.Contains(message, term[1:])
+		case strings.HasPrefix(term, "-"):
+			if strings.Contains(message, term[1:]) {
+				return false
+			}
+		default:
+			if !strings.Contains(message, term) {
+				return false
+			}
+		}
+	}
+	return !anyOf || anyMatched
+}
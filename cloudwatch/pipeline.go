@@ -0,0 +1,274 @@
+package cloudwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+//Pipeline delivers the events of a log group to S3 through a subscription filter and a Firehose delivery stream
+//The filter, the delivery stream and the roles are all named after the pipeline
+type Pipeline struct {
+	Name  string
+	Group string
+}
+
+//NewPipeline returns the pipeline of the group, e.g. cw-ecs-api for /ecs/api
+//Long names are cut and suffixed with a hash of the group, so that groups sharing a long prefix get distinct pipelines
+func NewPipeline(group string) *Pipeline {
+	name := "cw-" + strings.Trim(regexp.MustCompile(`[^a-zA-Z0-9_.-]+`).ReplaceAllString(group, "-"), "-")
+	//leave room for the role suffixes within the 64 characters limit
+	if len(name) > 55 {
+		h := fnv.New32a()
+		h.Write([]byte(group))
+		name = fmt.Sprintf("%s-%08x", name[:46], h.Sum32())
+	}
+	return &Pipeline{Name: name, Group: group}
+}
+
+//FirehoseRole is the role the delivery stream writes to S3 with
+func (p *Pipeline) FirehoseRole() string {
+	return p.Name + "-firehose"
+}
+
+//LogsRole is the role CloudWatch Logs sends the events to the delivery stream with
+func (p *Pipeline) LogsRole() string {
+	return p.Name + "-logs"
+}
+
+//DeliveryStream is a Firehose delivery stream with an S3 destination
+type DeliveryStream struct {
+	Name    string
+	Arn     string
+	Status  string
+	RoleArn string
+	Bucket  string
+	Prefix  string
+}
+
+//The Firehose processors decompressing the CloudWatch Logs records and delimiting them with new lines
+//They're newer than the SDK, which only knows about the Lambda processor
+const (
+	processorDecompression   = "Decompression"
+	processorAppendDelimiter = "AppendDelimiterToRecord"
+)
+
+type policyDocument struct {
+	Version   string
+	Statement []policyStatement
+}
+
+type policyStatement struct {
+	Effect    string
+	Principal map[string]string `json:",omitempty"`
+	Action    []string
+	Resource  []string `json:",omitempty"`
+}
+
+func policyJSON(statements ...policyStatement) string {
+	b, _ := json.Marshal(policyDocument{Version: "2012-10-17", Statement: statements})
+	return string(b)
+}
+
+//FirehoseRolePolicy allows the delivery stream to write under the S3 prefix
+func FirehoseRolePolicy(bucket string, prefix string) (string, string) {
+	trust := policyJSON(policyStatement{Effect: "Allow", Principal: map[string]string{"Service": "firehose.amazonaws.com"}, Action: []string{"sts:AssumeRole"}})
+	objects := fmt.Sprintf("arn:aws:s3:::%s/*", bucket)
+	if prefix != "" {
+		objects = fmt.Sprintf("arn:aws:s3:::%s/%s/*", bucket, prefix)
+	}
+	policy := policyJSON(
+		policyStatement{Effect: "Allow", Action: []string{"s3:GetBucketLocation", "s3:ListBucket", "s3:ListBucketMultipartUploads"}, Resource: []string{"arn:aws:s3:::" + bucket}},
+		policyStatement{Effect: "Allow", Action: []string{"s3:AbortMultipartUpload", "s3:GetObject", "s3:PutObject"}, Resource: []string{objects}},
+	)
+	return trust, policy
+}
+
+//LogsRolePolicy allows CloudWatch Logs of the region to send records to the delivery stream
+func LogsRolePolicy(region string, deliveryStreamArn string) (string, string) {
+	trust := policyJSON(policyStatement{Effect: "Allow", Principal: map[string]string{"Service": fmt.Sprintf("logs.%s.amazonaws.com", region)}, Action: []string{"sts:AssumeRole"}})
+	policy := policyJSON(policyStatement{Effect: "Allow", Action: []string{"firehose:PutRecord", "firehose:PutRecordBatch"}, Resource: []string{deliveryStreamArn}})
+	return trust, policy
+}
+
+//EnsureRole creates the role when missing and sets its inline policy
+//It returns the role ARN
+func EnsureRole(t Target, name string, trust string, policy string) (string, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return "", err
+	}
+	client := iam.New(sess)
+	var roleArn string
+	res, err := client.CreateRole(&iam.CreateRoleInput{RoleName: aws.String(name), AssumeRolePolicyDocument: aws.String(trust),
+		Description: aws.String("Created by cw pipeline")})
+	switch {
+	case err == nil:
+		roleArn = *res.Role.Arn
+	case isErrCode(err, iam.ErrCodeEntityAlreadyExistsException):
+		role, err := client.GetRole(&iam.GetRoleInput{RoleName: aws.String(name)})
+		if err != nil {
+			return "", err
+		}
+		roleArn = *role.Role.Arn
+	default:
+		return "", err
+	}
+	_, err = client.PutRolePolicy(&iam.PutRolePolicyInput{RoleName: aws.String(name), PolicyName: aws.String(name), PolicyDocument: aws.String(policy)})
+	return roleArn, err
+}
+
+//RoleArn returns the ARN of the role, empty if the role doesn't exist
+func RoleArn(t Target, name string) (string, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return "", err
+	}
+	role, err := iam.New(sess).GetRole(&iam.GetRoleInput{RoleName: aws.String(name)})
+	if isErrCode(err, iam.ErrCodeNoSuchEntityException) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return *role.Role.Arn, nil
+}
+
+//DeleteRole deletes the role and its inline policies, if the role exists
+func DeleteRole(t Target, name string) error {
+	sess, err := newSession(t)
+	if err != nil {
+		return err
+	}
+	client := iam.New(sess)
+	policies, err := client.ListRolePolicies(&iam.ListRolePoliciesInput{RoleName: aws.String(name)})
+	if isErrCode(err, iam.ErrCodeNoSuchEntityException) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, p := range policies.PolicyNames {
+		if _, err := client.DeleteRolePolicy(&iam.DeleteRolePolicyInput{RoleName: aws.String(name), PolicyName: p}); err != nil {
+			return err
+		}
+	}
+	_, err = client.DeleteRole(&iam.DeleteRoleInput{RoleName: aws.String(name)})
+	return err
+}
+
+func firehoseClientFor(t Target) (*firehose.Firehose, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return nil, err
+	}
+	return firehose.New(sess), nil
+}
+
+//DescribeDeliveryStream returns the delivery stream, nil if it doesn't exist
+func DescribeDeliveryStream(t Target, name string) (*DeliveryStream, error) {
+	client, err := firehoseClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.DescribeDeliveryStream(&firehose.DescribeDeliveryStreamInput{DeliveryStreamName: aws.String(name)})
+	if isErrCode(err, firehose.ErrCodeResourceNotFoundException) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d := res.DeliveryStreamDescription
+	s := &DeliveryStream{Name: name, Arn: *d.DeliveryStreamARN, Status: *d.DeliveryStreamStatus}
+	for _, dest := range d.Destinations {
+		if s3 := dest.ExtendedS3DestinationDescription; s3 != nil {
+			s.RoleArn = aws.StringValue(s3.RoleARN)
+			s.Bucket = strings.TrimPrefix(aws.StringValue(s3.BucketARN), "arn:aws:s3:::")
+			s.Prefix = strings.TrimSuffix(aws.StringValue(s3.Prefix), "/")
+		}
+	}
+	return s, nil
+}
+
+//isRolePropagation reports whether Firehose can't assume the role yet, a new role can take a few seconds to be assumable
+//The other invalid arguments are configuration errors
+func isRolePropagation(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == firehose.ErrCodeInvalidArgumentException && strings.Contains(awsErr.Message(), "unable to assume role")
+}
+
+//EnsureDeliveryStream creates the delivery stream to the S3 prefix when missing and waits for it to be active
+//The records are decompressed and stored one per line in .gz objects, as Athena reads them,
+//under the prefix/YYYY/MM/DD/HH/ partitions
+func EnsureDeliveryStream(t Target, name string, roleArn string, bucket string, prefix string, timeout time.Duration) (*DeliveryStream, error) {
+	client, err := firehoseClientFor(t)
+	if err != nil {
+		return nil, err
+	}
+	s3 := &firehose.ExtendedS3DestinationConfiguration{
+		BucketARN:         aws.String("arn:aws:s3:::" + bucket),
+		RoleARN:           aws.String(roleArn),
+		CompressionFormat: aws.String(firehose.CompressionFormatGzip),
+		BufferingHints:    &firehose.BufferingHints{IntervalInSeconds: aws.Int64(60), SizeInMBs: aws.Int64(5)},
+		ProcessingConfiguration: &firehose.ProcessingConfiguration{
+			Enabled: aws.Bool(true),
+			Processors: []*firehose.Processor{
+				{Type: aws.String(processorDecompression), Parameters: []*firehose.ProcessorParameter{
+					{ParameterName: aws.String("CompressionFormat"), ParameterValue: aws.String("GZIP")},
+				}},
+				{Type: aws.String(processorAppendDelimiter)},
+			},
+		},
+	}
+	if prefix != "" {
+		s3.Prefix = aws.String(prefix + "/")
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		s, err := DescribeDeliveryStream(t, name)
+		if err != nil {
+			return nil, err
+		}
+		if s != nil && s.Status == firehose.DeliveryStreamStatusActive {
+			return s, nil
+		}
+		if s != nil && s.Status != firehose.DeliveryStreamStatusCreating {
+			return nil, fmt.Errorf("delivery stream %s is %s", name, s.Status)
+		}
+		if s == nil {
+			_, err = client.CreateDeliveryStream(&firehose.CreateDeliveryStreamInput{DeliveryStreamName: aws.String(name),
+				DeliveryStreamType: aws.String(firehose.DeliveryStreamTypeDirectPut), ExtendedS3DestinationConfiguration: s3})
+			if err != nil && !isRolePropagation(err) {
+				return nil, err
+			}
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = fmt.Errorf("timed out")
+			}
+			return nil, fmt.Errorf("delivery stream %s not active: %s", name, err)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+//DeleteDeliveryStream deletes the delivery stream, if it exists
+func DeleteDeliveryStream(t Target, name string) error {
+	client, err := firehoseClientFor(t)
+	if err != nil {
+		return err
+	}
+	_, err = client.DeleteDeliveryStream(&firehose.DeleteDeliveryStreamInput{DeliveryStreamName: aws.String(name)})
+	if isErrCode(err, firehose.ErrCodeResourceNotFoundException) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+package cloudwatch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPipeline(t *testing.T) {
+	assert.Equal(t, "cw-ecs-api", NewPipeline("/ecs/api").Name)
+
+	prefix := "/aws/lambda/" + strings.Repeat("very-long-function-name-", 3)
+	a, b := NewPipeline(prefix+"orders"), NewPipeline(prefix+"payments")
+	assert.NotEqual(t, a.Name, b.Name)
+	assert.Len(t, a.Name, 55)
+	assert.True(t, len(a.FirehoseRole()) <= 64)
+	assert.Equal(t, a.Name, NewPipeline(prefix+"orders").Name)
+}
+
+func TestIsRolePropagation(t *testing.T) {
+	assert.True(t, isRolePropagation(awserr.New(firehose.ErrCodeInvalidArgumentException, "Firehose is unable to assume role arn:aws:iam::123456789012:role/cw-firehose. Please check the role provided.", nil)))
+	assert.False(t, isRolePropagation(awserr.New(firehose.ErrCodeInvalidArgumentException, "BufferingHints.SizeInMBs must be at least 64 when data format conversion is enabled.", nil)))
+	assert.False(t, isRolePropagation(awserr.New(firehose.ErrCodeLimitExceededException, "unable to assume role", nil)))
+	assert.False(t, isRolePropagation(errors.New("unable to assume role")))
+}
@@ -0,0 +1,156 @@
+package cloudwatch
+
+import (
+	"regexp"
+	"sort"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+//PutLogEvents limits
+const (
+	MaxBatchEvents = 10000
+	MaxBatchBytes  = 1048576
+)
+
+//maxTokenRetries caps the retries of a batch rejected for its sequence token, other writers may keep getting in the way
+const maxTokenRetries = 5
+
+var expectedTokenRe = regexp.MustCompile(`sequenceToken(?: is)?: (\S+)`)
+
+//expectedToken returns the sequence token an error message says the service expects, nil for the first put of a stream
+func expectedToken(message string) (*string, bool) {
+	m := expectedTokenRe.FindStringSubmatch(message)
+	if m == nil {
+		return nil, false
+	}
+	if m[1] == "null" {
+		return nil, true
+	}
+	return aws.String(m[1]), true
+}
+
+//MessageSize is the size of an event with the message counted against MaxBatchBytes
+func MessageSize(message string) int {
+	return len(message) + eventOverhead
+}
+
+//BatchFull reports whether a batch of the given number of events and bytes can't take an event of the given size
+func BatchFull(events int, bytes int, size int) bool {
+	return events >= MaxBatchEvents || bytes+size > MaxBatchBytes
+}
+
+//StreamWriter writes events to a log stream keeping track of its sequence token
+type StreamWriter struct {
+	cwl       *cloudwatchlogs.CloudWatchLogs
+	group     string
+	stream    string
+	token     *string
+	throttles int64
+}
+
+//NewStreamWriter returns a writer for the given stream, creating the group and the stream when missing
+func NewStreamWriter(target Target, group string, stream string) (*StreamWriter, error) {
+	cwl, err := cwClientFor(target)
+	if err != nil {
+		return nil, err
+	}
+	w := &StreamWriter{cwl: cwl, group: group, stream: stream}
+	cwl.Handlers.Retry.PushFront(func(r *request.Request) {
+		if awsErr, ok := r.Error.(awserr.Error); ok && awsErr.Code() == "ThrottlingException" {
+			atomic.AddInt64(&w.throttles, 1)
+		}
+	})
+
+	if err := ensureGroup(cwl, group); err != nil {
+		return nil, err
+	}
+	_, err = cwl.CreateLogStream(&cloudwatchlogs.CreateLogStreamInput{LogGroupName: aws.String(group), LogStreamName: aws.String(stream)})
+	if err != nil && !isErrCode(err, cloudwatchlogs.ErrCodeResourceAlreadyExistsException) {
+		return nil, err
+	}
+	streams, err := cwl.DescribeLogStreams(&cloudwatchlogs.DescribeLogStreamsInput{LogGroupName: aws.String(group), LogStreamNamePrefix: aws.String(stream)})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range streams.LogStreams {
+		if *s.LogStreamName == stream {
+			w.token = s.UploadSequenceToken
+		}
+	}
+	return w, nil
+}
+
+//Throttles returns how many times the writes have been throttled
+func (w *StreamWriter) Throttles() int64 {
+	return atomic.LoadInt64(&w.throttles)
+}
+
+//Put writes a batch of events, sorting them by timestamp as PutLogEvents requires
+//The batch must respect the MaxBatchEvents and MaxBatchBytes limits
+func (w *StreamWriter) Put(events []*cloudwatchlogs.InputLogEvent) error {
+	sort.SliceStable(events, func(i, j int) bool { return *events[i].Timestamp < *events[j].Timestamp })
+	for retries := 0; ; retries++ {
+		params := &cloudwatchlogs.PutLogEventsInput{
+			LogGroupName:  aws.String(w.group),
+			LogStreamName: aws.String(w.stream),
+			LogEvents:     events,
+			SequenceToken: w.token,
+		}
+		res, err := w.cwl.PutLogEvents(params)
+		if err == nil {
+			w.token = res.NextSequenceToken
+			return nil
+		}
+		awsErr, ok := err.(awserr.Error)
+		if !ok {
+			return err
+		}
+		switch awsErr.Code() {
+		case cloudwatchlogs.ErrCodeInvalidSequenceTokenException:
+			//another writer got in the way, retry with the token the service expects
+			token, ok := expectedToken(awsErr.Message())
+			if !ok || retries == maxTokenRetries {
+				return err
+			}
+			w.token = token
+		case cloudwatchlogs.ErrCodeDataAlreadyAcceptedException:
+			if token, ok := expectedToken(awsErr.Message()); ok {
+				w.token = token
+			}
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+//DeleteStream deletes the stream of the group, a missing stream is not an error
+func DeleteStream(target Target, group string, stream string) error {
+	cwl, err := cwClientFor(target)
+	if err != nil {
+		return err
+	}
+	_, err = cwl.DeleteLogStream(&cloudwatchlogs.DeleteLogStreamInput{LogGroupName: aws.String(group), LogStreamName: aws.String(stream)})
+	if isErrCode(err, cloudwatchlogs.ErrCodeResourceNotFoundException) {
+		return nil
+	}
+	return err
+}
+
+func ensureGroup(cwl *cloudwatchlogs.CloudWatchLogs, group string) error {
+	_, err := cwl.CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{LogGroupName: aws.String(group)})
+	if err != nil && !isErrCode(err, cloudwatchlogs.ErrCodeResourceAlreadyExistsException) {
+		return err
+	}
+	return nil
+}
+
+func isErrCode(err error, code string) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == code
+}
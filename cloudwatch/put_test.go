@@ -0,0 +1,39 @@
+package cloudwatch
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpectedToken(t *testing.T) {
+	token, ok := expectedToken("The given sequenceToken is invalid. The next expected sequenceToken is: 4960123")
+	assert.True(t, ok)
+	assert.Equal(t, "4960123", aws.StringValue(token))
+
+	token, ok = expectedToken("Log event already accepted. The next batch can be sent with sequenceToken: 4960124")
+	assert.True(t, ok)
+	assert.Equal(t, "4960124", aws.StringValue(token))
+
+	token, ok = expectedToken("The given sequenceToken is invalid. The next expected sequenceToken is: null")
+	assert.True(t, ok)
+	assert.Nil(t, token)
+
+	_, ok = expectedToken("The given sequenceToken is invalid.")
+	assert.False(t, ok)
+}
+
+func TestMessageSize(t *testing.T) {
+	assert.Equal(t, 26, MessageSize(""))
+	assert.Equal(t, 31, MessageSize("hello"))
+}
+
+func TestBatchFull(t *testing.T) {
+	assert.False(t, BatchFull(0, 0, MaxBatchBytes))
+	assert.True(t, BatchFull(0, 0, MaxBatchBytes+1))
+	assert.False(t, BatchFull(MaxBatchEvents-1, 0, 26))
+	assert.True(t, BatchFull(MaxBatchEvents, 0, 26))
+	assert.False(t, BatchFull(10, MaxBatchBytes-100, 100))
+	assert.True(t, BatchFull(10, MaxBatchBytes-100, 101))
+}
@@ -0,0 +1,85 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+//ParseS3URL splits an s3://bucket/prefix URL into bucket and prefix
+//The prefix is returned without leading and trailing slashes
+func ParseS3URL(s string) (string, string, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid S3 URL %q, expected s3://bucket/prefix", s)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+//S3Object is an object stored in S3
+type S3Object struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+func s3ClientFor(t Target) (*s3.S3, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(sess), nil
+}
+
+//ListObjects returns the objects stored under the given bucket prefix
+func ListObjects(target Target, bucket string, prefix string) ([]S3Object, error) {
+	client, err := s3ClientFor(target)
+	if err != nil {
+		return nil, err
+	}
+	var objects []S3Object
+	params := &s3.ListObjectsV2Input{Bucket: aws.String(bucket), Prefix: aws.String(prefix)}
+	err = client.ListObjectsV2Pages(params, func(res *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, o := range res.Contents {
+			objects = append(objects, S3Object{Key: aws.StringValue(o.Key), Size: aws.Int64Value(o.Size)})
+		}
+		return !lastPage
+	})
+	return objects, err
+}
+
+//GzipSize returns the uncompressed size(modulo 2^32) of a gzip object reading its trailer
+func GzipSize(target Target, bucket string, key string) (int64, error) {
+	client, err := s3ClientFor(target)
+	if err != nil {
+		return 0, err
+	}
+	res, err := client.GetObject(&s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key), Range: aws.String("bytes=-4")})
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	trailer, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+	if len(trailer) != 4 {
+		return 0, fmt.Errorf("s3://%s/%s is not a gzip object", bucket, key)
+	}
+	return int64(binary.LittleEndian.Uint32(trailer)), nil
+}
+
+//PutObject stores the content in the given bucket and key
+func PutObject(target Target, bucket string, key string, content []byte) error {
+	client, err := s3ClientFor(target)
+	if err != nil {
+		return err
+	}
+	_, err = client.PutObject(&s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key), Body: bytes.NewReader(content)})
+	return err
+}
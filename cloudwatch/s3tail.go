@@ -0,0 +1,122 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+//firehoseLateness is how long after the event time Firehose can deliver a record
+//Objects are partitioned by delivery time, so the partitions following the end time are read as well
+const firehoseLateness = time.Hour
+
+//TailS3 reads the CloudWatch Logs subscription records Firehose delivered to S3
+//Objects are expected under the default Firehose prefix/YYYY/MM/DD/HH/ time partitions
+//It returns a channel where the events of the matching log groups and stream(a prefix, '*' for all)
+//between startTime and endTime are published in timestamp order
+func TailS3(target Target, bucket string, prefix string, logGroupName string, logStreamName string, startTime time.Time, endTime time.Time) <-chan *cloudwatchlogs.FilteredLogEvent {
+	ch := make(chan *cloudwatchlogs.FilteredLogEvent)
+	fail := func(err error) {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	sess, err := newSession(target)
+	if err != nil {
+		fail(err)
+	}
+	downloader := s3manager.NewDownloader(sess)
+	start := startTime.UnixNano() / int64(time.Millisecond)
+	end := endTime.UnixNano() / int64(time.Millisecond)
+
+	readPartition := func(partition string) []*cloudwatchlogs.FilteredLogEvent {
+		objects, err := ListObjects(target, bucket, partition)
+		if err != nil {
+			fail(err)
+		}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var events []*cloudwatchlogs.FilteredLogEvent
+		sem := make(chan struct{}, 8)
+		for _, o := range objects {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				buf := aws.NewWriteAtBuffer(nil)
+				if _, err := downloader.Download(buf, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}); err != nil {
+					fail(err)
+				}
+				err := DecodeSubscriptionMessages(bytes.NewReader(buf.Bytes()), func(m *SubscriptionMessage) error {
+					for _, event := range m.Events(logGroupName, logStreamName) {
+						if *event.Timestamp >= start && (endTime.IsZero() || *event.Timestamp <= end) {
+							mu.Lock()
+							events = append(events, event)
+							mu.Unlock()
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "cannot decode s3://%s/%s: %s\n", bucket, key, err)
+				}
+			}(o.Key)
+		}
+		wg.Wait()
+		return events
+	}
+
+	last := endTime
+	if last.IsZero() {
+		last = time.Now()
+	}
+	last = last.Add(firehoseLateness)
+	go func() {
+		//a partition can hold events up to firehoseLateness older than its hour, they're buffered
+		//until no partition left to read can hold older ones
+		var buf eventBuffer
+		for hour := startTime.UTC().Truncate(time.Hour); !hour.After(last); hour = hour.Add(time.Hour) {
+			partition := path.Join(prefix, hour.Format("2006/01/02/15")) + "/"
+			buf.add(readPartition(partition))
+			for _, event := range buf.flush(hour.Add(time.Hour).Add(-firehoseLateness)) {
+				ch <- event
+			}
+		}
+		for _, event := range buf.flush(time.Time{}) {
+			ch <- event
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+//eventBuffer orders the events read from many partitions by timestamp
+type eventBuffer struct {
+	events []*cloudwatchlogs.FilteredLogEvent
+}
+
+func (b *eventBuffer) add(events []*cloudwatchlogs.FilteredLogEvent) {
+	b.events = append(b.events, events...)
+	sort.SliceStable(b.events, func(i, j int) bool { return *b.events[i].Timestamp < *b.events[j].Timestamp })
+}
+
+//flush returns the events older than before in timestamp order, all of them when before is zero
+func (b *eventBuffer) flush(before time.Time) []*cloudwatchlogs.FilteredLogEvent {
+	n := len(b.events)
+	if !before.IsZero() {
+		ms := before.UnixNano() / int64(time.Millisecond)
+		n = sort.Search(len(b.events), func(i int) bool { return *b.events[i].Timestamp >= ms })
+	}
+	out := b.events[:n]
+	b.events = append([]*cloudwatchlogs.FilteredLogEvent(nil), b.events[n:]...)
+	return out
+}
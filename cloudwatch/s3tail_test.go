@@ -0,0 +1,38 @@
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+)
+
+func at(t time.Time) *cloudwatchlogs.FilteredLogEvent {
+	return &cloudwatchlogs.FilteredLogEvent{Timestamp: aws.Int64(t.UnixNano() / int64(time.Millisecond))}
+}
+
+func timestamps(events []*cloudwatchlogs.FilteredLogEvent) []int64 {
+	var ts []int64
+	for _, e := range events {
+		ts = append(ts, *e.Timestamp/1000)
+	}
+	return ts
+}
+
+func TestEventBufferMergesLateRecords(t *testing.T) {
+	h10 := time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+	var b eventBuffer
+
+	//the 10:00 partition, delivered late in the 11:00 one as well
+	b.add([]*cloudwatchlogs.FilteredLogEvent{at(h10.Add(50 * time.Minute)), at(h10.Add(-10 * time.Minute)), at(h10.Add(5 * time.Minute))})
+	assert.Equal(t, []int64{h10.Add(-10 * time.Minute).Unix()}, timestamps(b.flush(h10)))
+
+	b.add([]*cloudwatchlogs.FilteredLogEvent{at(h10.Add(70 * time.Minute)), at(h10.Add(20 * time.Minute))})
+	assert.Equal(t, []int64{h10.Add(5 * time.Minute).Unix(), h10.Add(20 * time.Minute).Unix(), h10.Add(50 * time.Minute).Unix()},
+		timestamps(b.flush(h10.Add(time.Hour))))
+
+	assert.Equal(t, []int64{h10.Add(70 * time.Minute).Unix()}, timestamps(b.flush(time.Time{})))
+	assert.Empty(t, b.flush(time.Time{}))
+}
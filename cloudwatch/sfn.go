@@ -0,0 +1,108 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/sfn"
+)
+
+//StateMachine is a Step Functions state machine
+type StateMachine struct {
+	Name string
+	Arn  string
+	//Execution is set when the state machine was found from an execution ARN
+	Execution string
+}
+
+//FindStateMachine returns the state machine of an execution ARN, or of a state machine ARN or name
+func FindStateMachine(t Target, ref string) (*StateMachine, error) {
+	sess, err := newSession(t)
+	if err != nil {
+		return nil, err
+	}
+	sc := sfn.New(sess)
+	if !strings.HasPrefix(ref, "arn:") {
+		var sm *StateMachine
+		err := sc.ListStateMachinesPages(&sfn.ListStateMachinesInput{}, func(res *sfn.ListStateMachinesOutput, lastPage bool) bool {
+			for _, m := range res.StateMachines {
+				if aws.StringValue(m.Name) == ref {
+					sm = &StateMachine{Name: ref, Arn: aws.StringValue(m.StateMachineArn)}
+					return false
+				}
+			}
+			return !lastPage
+		})
+		if err != nil {
+			return nil, err
+		}
+		if sm == nil {
+			return nil, fmt.Errorf("no such state machine: %s", ref)
+		}
+		return sm, nil
+	}
+
+	a, err := arn.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	//stateMachine:NAME, execution:NAME:ID or express:NAME:ID:UUID
+	parts := strings.Split(a.Resource, ":")
+	if a.Service != "states" || len(parts) < 2 {
+		return nil, fmt.Errorf("not a state machine or execution ARN: %s", ref)
+	}
+	sm := &StateMachine{}
+	switch parts[0] {
+	case "stateMachine":
+	case "execution", "express":
+		sm.Execution = ref
+	default:
+		return nil, fmt.Errorf("not a state machine or execution ARN: %s", ref)
+	}
+	a.Resource = "stateMachine:" + parts[1]
+	res, err := sc.DescribeStateMachine(&sfn.DescribeStateMachineInput{StateMachineArn: aws.String(a.String())})
+	if err != nil {
+		return nil, err
+	}
+	sm.Name, sm.Arn = aws.StringValue(res.Name), aws.StringValue(res.StateMachineArn)
+	return sm, nil
+}
+
+//StateMachineLogGroup returns the log group of the state machine
+//The SDK doesn't expose the logging configuration yet, so the group is found by the name
+//the console gives it, /aws/vendedlogs/states/NAME-Logs, or a group under /aws/vendedlogs/states/NAME/
+func StateMachineLogGroup(t Target, sm *StateMachine) (string, error) {
+	groups, err := DescribeGroups(t, "/aws/vendedlogs/states/"+sm.Name+"*")
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	return stateMachineGroup(sm.Name, names)
+}
+
+//stateMachineGroup picks the group of the state machine among the groups starting with its name
+//The groups of the state machines whose name starts with the same prefix, e.g. NAMEv2-Logs, are ignored
+func stateMachineGroup(name string, groups []string) (string, error) {
+	prefix := "/aws/vendedlogs/states/" + name
+	var names []string
+	for _, g := range groups {
+		if g == prefix+"-Logs" {
+			return g, nil
+		}
+		if strings.HasPrefix(g, prefix+"/") {
+			names = append(names, g)
+		}
+	}
+	switch len(names) {
+	case 0:
+		return "", fmt.Errorf("no log group found for the state machine %s, set it with --group", name)
+	case 1:
+		return names[0], nil
+	}
+	return "", fmt.Errorf("many log groups found for the state machine %s, set one with --group: %s", name, strings.Join(names, ", "))
+}
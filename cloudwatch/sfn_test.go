@@ -0,0 +1,27 @@
+package cloudwatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateMachineGroup(t *testing.T) {
+	g, err := stateMachineGroup("Orders", []string{"/aws/vendedlogs/states/Orders/express", "/aws/vendedlogs/states/Orders-Logs"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/aws/vendedlogs/states/Orders-Logs", g)
+
+	g, err = stateMachineGroup("Orders", []string{"/aws/vendedlogs/states/Orders/express", "/aws/vendedlogs/states/OrdersV2-Logs"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/aws/vendedlogs/states/Orders/express", g)
+}
+
+func TestStateMachineGroupOtherMachine(t *testing.T) {
+	_, err := stateMachineGroup("Orders", []string{"/aws/vendedlogs/states/OrdersV2-Logs"})
+	assert.EqualError(t, err, "no log group found for the state machine Orders, set it with --group")
+}
+
+func TestStateMachineGroupAmbiguous(t *testing.T) {
+	_, err := stateMachineGroup("Orders", []string{"/aws/vendedlogs/states/Orders/a", "/aws/vendedlogs/states/Orders/b"})
+	assert.EqualError(t, err, "many log groups found for the state machine Orders, set one with --group: /aws/vendedlogs/states/Orders/a, /aws/vendedlogs/states/Orders/b")
+}
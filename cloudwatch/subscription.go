@@ -0,0 +1,72 @@
+package cloudwatch
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+//Subscription message types
+const (
+	DataMessage    = "DATA_MESSAGE"
+	ControlMessage = "CONTROL_MESSAGE"
+)
+
+//SubscriptionMessage is the payload CloudWatch Logs delivers to the subscription filter destinations
+type SubscriptionMessage struct {
+	MessageType         string   `json:"messageType"`
+	Owner               string   `json:"owner"`
+	LogGroup            string   `json:"logGroup"`
+	LogStream           string   `json:"logStream"`
+	SubscriptionFilters []string `json:"subscriptionFilters"`
+	LogEvents           []struct {
+		ID        string `json:"id"`
+		Timestamp int64  `json:"timestamp"`
+		Message   string `json:"message"`
+	} `json:"logEvents"`
+}
+
+//Events returns the log events of a data message
+//The log group is matched as a pattern(see MatchGroup), the log stream as a prefix, '*' matching all the streams
+func (m *SubscriptionMessage) Events(groupPattern string, streamPrefix string) []*cloudwatchlogs.FilteredLogEvent {
+	if m.MessageType != DataMessage || !MatchGroup(groupPattern, m.LogGroup) || (streamPrefix != "*" && !strings.HasPrefix(m.LogStream, streamPrefix)) {
+		return nil
+	}
+	events := make([]*cloudwatchlogs.FilteredLogEvent, 0, len(m.LogEvents))
+	for _, e := range m.LogEvents {
+		events = append(events, &cloudwatchlogs.FilteredLogEvent{
+			EventId:       aws.String(e.ID),
+			Timestamp:     aws.Int64(e.Timestamp),
+			IngestionTime: aws.Int64(e.Timestamp),
+			LogStreamName: aws.String(m.LogStream),
+			Message:       aws.String(e.Message),
+		})
+	}
+	return events
+}
+
+//DecodeSubscriptionMessages decodes gzip compressed subscription messages, calling fn for each of them
+//Concatenated gzip members, as Firehose writes them to S3, are decoded one after the other
+func DecodeSubscriptionMessages(r io.Reader, fn func(*SubscriptionMessage) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	dec := json.NewDecoder(gz)
+	for {
+		var m SubscriptionMessage
+		if err := dec.Decode(&m); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(&m); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const dataMessage = `{"messageType":"DATA_MESSAGE","owner":"123456789012","logGroup":"/ecs/api","logStream":"web/1","subscriptionFilters":["cw-ecs-api"],"logEvents":[{"id":"1","timestamp":1530439200000,"message":"GET /"}]}`
+const controlMessage = `{"messageType":"CONTROL_MESSAGE","owner":"CloudwatchLogs","logGroup":"","logStream":"","subscriptionFilters":[],"logEvents":[{"id":"","timestamp":1530439200000,"message":"CWL CONTROL MESSAGE: Checking health of destination Firehose."}]}`
+
+func gzipped(members ...string) []byte {
+	var b bytes.Buffer
+	for _, m := range members {
+		w := gzip.NewWriter(&b)
+		w.Write([]byte(m))
+		w.Close()
+	}
+	return b.Bytes()
+}
+
+func decodeAll(t *testing.T, object []byte) []*SubscriptionMessage {
+	var messages []*SubscriptionMessage
+	err := DecodeSubscriptionMessages(bytes.NewReader(object), func(m *SubscriptionMessage) error {
+		messages = append(messages, m)
+		return nil
+	})
+	assert.NoError(t, err)
+	return messages
+}
+
+//the records as CloudWatch Logs sends them, gzipped one by one and concatenated without delimiters
+func TestDecodeConcatenatedMembers(t *testing.T) {
+	messages := decodeAll(t, gzipped(controlMessage, dataMessage, dataMessage))
+	assert.Len(t, messages, 3)
+	assert.Empty(t, messages[0].Events("*", "*"))
+	events := messages[1].Events("/ecs/*", "web")
+	assert.Len(t, events, 1)
+	assert.Equal(t, "GET /", *events[0].Message)
+}
+
+//the records decompressed by Firehose, one per line in a gzipped object
+func TestDecodeDelimitedRecords(t *testing.T) {
+	messages := decodeAll(t, gzipped(controlMessage+"\n"+dataMessage+"\n"+dataMessage+"\n"))
+	assert.Len(t, messages, 3)
+	assert.Equal(t, "/ecs/api", messages[2].LogGroup)
+	assert.Empty(t, messages[2].Events("/lambda/*", "*"))
+}
@@ -0,0 +1,51 @@
+package cloudwatch
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+//eventOverhead is the per event size CloudWatch adds to the message when metering ingestion
+const eventOverhead = 26
+
+//Sample holds the event volume observed in a log group over a time window
+type Sample struct {
+	Events int64
+	Bytes  int64
+	Window time.Duration
+}
+
+//BytesPerSecond returns the ingestion rate observed by the sample
+func (s Sample) BytesPerSecond() float64 {
+	if s.Window <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Window.Seconds()
+}
+
+//SampleIngestion measures the volume of the events ingested in the given group over the last window
+//At most maxEvents are read: when the limit is hit the window is shrunk to the time span actually read
+func SampleIngestion(target Target, groupName string, window time.Duration, maxEvents int64) (Sample, error) {
+	end := time.Now()
+	start := end.Add(-window)
+
+	sample := Sample{Window: window}
+	var lastTimestamp int64
+	err := Events(target, Query{Group: groupName, Start: start, End: end}, func(event *cloudwatchlogs.FilteredLogEvent) bool {
+		sample.Events++
+		sample.Bytes += EventSize(event)
+		if ts := aws.Int64Value(event.Timestamp); ts > lastTimestamp {
+			lastTimestamp = ts
+		}
+		return sample.Events < maxEvents
+	})
+	if err != nil {
+		return Sample{}, err
+	}
+	if sample.Events >= maxEvents && lastTimestamp > 0 {
+		sample.Window = time.Duration(lastTimestamp-start.Unix()*1000) * time.Millisecond
+	}
+	return sample, nil
+}
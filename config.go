@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+
+	"github.com/go-ini/ini"
+)
+
+//config is the cw configuration, read from ~/.cw/config
+//
+//	[protected]
+//	profiles = prod, prod-admin
+//	accounts = 123456789012
+type config struct {
+	protectedProfiles []string
+	protectedAccounts []string
+}
+
+func loadConfig() (*config, error) {
+	p, err := cwPath("config")
+	if err != nil {
+		return nil, err
+	}
+	c := &config{}
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return c, nil
+	}
+	f, err := ini.Load(p)
+	if err != nil {
+		return nil, err
+	}
+	protected := f.Section("protected")
+	c.protectedProfiles = protected.Key("profiles").Strings(",")
+	c.protectedAccounts = protected.Key("accounts").Strings(",")
+	return c, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
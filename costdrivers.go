@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	costDriversCommand = kingpin.Command("cost-drivers", "Rank the message templates of a log group by estimated volume.")
+	costDriversGroup   = costDriversCommand.Arg("group", "The log group name.").Required().HintAction(groupsCompletion).String()
+	costDriversSince   = costDriversCommand.Flag("since", "How far back to look.").Default("24h").Duration()
+	costDriversSample  = costDriversCommand.Flag("sample", "Share of the time window to read, e.g. 5%.").Default("5%").String()
+	costDriversTop     = costDriversCommand.Flag("top", "Number of templates to show.").Default("10").Int()
+	costDriversOutput  = costDriversCommand.Flag("output", "Output format.").Short('o').Default("table").Enum("table", "json")
+)
+
+//sampleSlices is the number of evenly spaced slices the sampled share of the window is split into
+const sampleSlices = 24
+
+const maxExamples = 3
+
+//hexTokenLen is the minimum length of the hex tokens without 0x, shorter ones are words like ec2 or a1
+const hexTokenLen = 8
+
+var templateRules = []struct {
+	re          *regexp.Regexp
+	placeholder string
+	//only optionally restricts the matches replaced
+	only func(string) bool
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>", nil},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>", nil},
+	{regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}(:\d+)?\b`), "<ip>", nil},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>", nil},
+	{regexp.MustCompile(fmt.Sprintf(`\b[0-9a-fA-F]{%d,}\b`, hexTokenLen)), "<hex>", isHexToken},
+	//the numbers starting a word, followed by their unit like 12ms, the digits within words like ec2 are kept
+	{regexp.MustCompile(`-?\b\d+(\.\d+)?`), "<num>", nil},
+	{regexp.MustCompile(`\s+`), " ", nil},
+}
+
+//messageTemplate normalises a log message replacing its variable parts with placeholders
+func messageTemplate(msg string) string {
+	t := strings.TrimSpace(msg)
+	for _, rule := range templateRules {
+		if rule.only == nil {
+			t = rule.re.ReplaceAllString(t, rule.placeholder)
+			continue
+		}
+		only, placeholder := rule.only, rule.placeholder
+		t = rule.re.ReplaceAllStringFunc(t, func(m string) string {
+			if only(m) {
+				return placeholder
+			}
+			return m
+		})
+	}
+	if r := []rune(t); len(r) > 200 {
+		t = string(r[:200])
+	}
+	return t
+}
+
+//isHexToken reports whether a token mixes digits and letters, all digits are numbers and all letters are words
+func isHexToken(s string) bool {
+	return strings.ContainsAny(s, "0123456789") && strings.ContainsAny(s, "abcdefABCDEF")
+}
+
+type costDriver struct {
+	Template string           `json:"template"`
+	Events   int64            `json:"estimatedEvents"`
+	Bytes    int64            `json:"estimatedBytes"`
+	Share    float64          `json:"share"`
+	Examples []string         `json:"examples"`
+	Streams  map[string]int64 `json:"streams"`
+}
+
+//topStreams returns the n streams emitting most of the template events
+func (d *costDriver) topStreams(n int) []string {
+	var streams []string
+	for s := range d.Streams {
+		streams = append(streams, s)
+	}
+	sort.Slice(streams, func(i, j int) bool { return d.Streams[streams[i]] > d.Streams[streams[j]] })
+	if len(streams) > n {
+		streams = streams[:n]
+	}
+	return streams
+}
+
+func parseShare(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || v <= 0 || v > 100 {
+		return 0, fmt.Errorf("invalid sample %q, expected a percentage in (0, 100]", s)
+	}
+	return v / 100, nil
+}
+
+func costDrivers() {
+	share, err := parseShare(*costDriversSample)
+	kingpin.FatalIfError(err, "")
+
+	end := time.Now()
+	start := end.Add(-*costDriversSince)
+	slice := *costDriversSince / sampleSlices
+
+	drivers := make(map[string]*costDriver)
+	var totalBytes int64
+	for i := 0; i < sampleSlices; i++ {
+		sliceStart := start.Add(time.Duration(i) * slice)
+		q := cloudwatch.Query{Group: *costDriversGroup, Start: sliceStart, End: sliceStart.Add(time.Duration(float64(slice) * share))}
+		err := cloudwatch.Events(cloudwatch.Target{}, q, func(event *cloudwatchlogs.FilteredLogEvent) bool {
+			msg := aws.StringValue(event.Message)
+			t := messageTemplate(msg)
+			d, ok := drivers[t]
+			if !ok {
+				d = &costDriver{Template: t, Streams: make(map[string]int64)}
+				drivers[t] = d
+			}
+			size := cloudwatch.EventSize(event)
+			d.Events++
+			d.Bytes += size
+			totalBytes += size
+			d.Streams[aws.StringValue(event.LogStreamName)]++
+			if len(d.Examples) < maxExamples {
+				d.Examples = append(d.Examples, strings.TrimSpace(msg))
+			}
+			return true
+		})
+		kingpin.FatalIfError(err, "cannot read %s", *costDriversGroup)
+	}
+
+	var ranked []*costDriver
+	for _, d := range drivers {
+		d.Share = float64(d.Bytes) / float64(totalBytes)
+		d.Events = int64(float64(d.Events) / share)
+		d.Bytes = int64(float64(d.Bytes) / share)
+		ranked = append(ranked, d)
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].Bytes > ranked[j].Bytes })
+	if len(ranked) > *costDriversTop {
+		ranked = ranked[:*costDriversTop]
+	}
+
+	if *costDriversOutput == "json" {
+		if ranked == nil {
+			ranked = []*costDriver{}
+		}
+		printJSON(ranked)
+		return
+	}
+	if len(ranked) == 0 {
+		fmt.Println("No events sampled.")
+		return
+	}
+	for i, d := range ranked {
+		fmt.Printf("%s %s\n", color.YellowString("#%d", i+1), d.Template)
+		fmt.Printf("   %s ~%d events, ~%s, %.1f%% of the volume\n", color.GreenString("volume:"), d.Events, formatBytes(d.Bytes), d.Share*100)
+		fmt.Printf("   %s %s\n", color.BlueString("streams:"), strings.Join(d.topStreams(3), ", "))
+		for _, e := range d.Examples {
+			fmt.Printf("   %s %s\n", color.CyanString(">"), e)
+		}
+		fmt.Println("")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageTemplate(t *testing.T) {
+	cases := map[string]string{
+		"request 3f2a9c1e-8b7d-4e6f-a1b2-c3d4e5f6a7b8 done in 12.5ms": "request <uuid> done in <num>ms",
+		"2018-07-01T10:00:00Z GET /users/42 from 10.0.0.1:443":        "<time> GET /users/<num> from <ip>",
+		"commit 9fceb02d0ae598e95dc970b74767f19372d61af8 pushed":      "commit <hex> pushed",
+		"pointer 0xc000123abc   freed":                                "pointer <hex> freed",
+		"ec2 instance in a1 zone":                                     "ec2 instance in a1 zone",
+		"retry -3 after 200ms, id=42":                                 "retry <num> after <num>ms, id=<num>",
+		"deadbeef accepted":                                           "deadbeef accepted",
+	}
+	for msg, want := range cases {
+		assert.Equal(t, want, messageTemplate(msg), msg)
+	}
+}
+
+func TestMessageTemplateTruncatesRunes(t *testing.T) {
+	tpl := messageTemplate(strings.Repeat("é", 300))
+	assert.True(t, utf8.ValidString(tpl))
+	assert.Equal(t, 200, utf8.RuneCountInString(tpl))
+}
+
+func TestParseShare(t *testing.T) {
+	v, err := parseShare("5%")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.05, v)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	diffCommand = kingpin.Command("diff", "Compare the log groups configuration of two environments.")
+	diffLeft    = diffCommand.Flag("left", "The left environment as profile:region.").Required().String()
+	diffRight   = diffCommand.Flag("right", "The right environment as profile:region.").Required().String()
+	diffPattern = diffCommand.Flag("pattern", "Only compare the log groups matching the pattern, e.g. '/ecs/*'.").Default("*").String()
+	diffOutput  = diffCommand.Flag("output", "Output format.").Short('o').Default("table").Enum("table", "json")
+)
+
+type difference struct {
+	Group string `json:"group"`
+	Field string `json:"field"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+func diffMaps(group string, field string, left map[string]string, right map[string]string) []difference {
+	var diffs []difference
+	keys := make(map[string]bool)
+	for k := range left {
+		keys[k] = true
+	}
+	for k := range right {
+		keys[k] = true
+	}
+	for k := range keys {
+		l, inLeft := left[k]
+		r, inRight := right[k]
+		if inLeft && inRight && l == r {
+			continue
+		}
+		if !inLeft {
+			l = "-"
+		}
+		if !inRight {
+			r = "-"
+		}
+		diffs = append(diffs, difference{group, fmt.Sprintf("%s[%s]", field, k), l, r})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+func formatRetention(days int64) string {
+	if days == 0 {
+		return "never expire"
+	}
+	return strconv.FormatInt(days, 10) + "d"
+}
+
+func formatEncryption(kmsKeyID string) string {
+	//key IDs are unique per account, only the encryption setting is comparable
+	if kmsKeyID == "" {
+		return "none"
+	}
+	return "kms"
+}
+
+func diffGroups(left []*cloudwatch.LogGroup, right []*cloudwatch.LogGroup) []difference {
+	byName := func(groups []*cloudwatch.LogGroup) map[string]*cloudwatch.LogGroup {
+		m := make(map[string]*cloudwatch.LogGroup)
+		for _, g := range groups {
+			m[g.Name] = g
+		}
+		return m
+	}
+	l, r := byName(left), byName(right)
+
+	var names []string
+	for name := range l {
+		names = append(names, name)
+	}
+	for name := range r {
+		if _, ok := l[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []difference
+	for _, name := range names {
+		lg, rg := l[name], r[name]
+		if lg == nil || rg == nil {
+			d := difference{name, "exists", "yes", "yes"}
+			if lg == nil {
+				d.Left = "no"
+			} else {
+				d.Right = "no"
+			}
+			diffs = append(diffs, d)
+			continue
+		}
+		if lg.RetentionInDays != rg.RetentionInDays {
+			diffs = append(diffs, difference{name, "retention", formatRetention(lg.RetentionInDays), formatRetention(rg.RetentionInDays)})
+		}
+		if formatEncryption(lg.KmsKeyID) != formatEncryption(rg.KmsKeyID) {
+			diffs = append(diffs, difference{name, "encryption", formatEncryption(lg.KmsKeyID), formatEncryption(rg.KmsKeyID)})
+		}
+		diffs = append(diffs, diffMaps(name, "tag", lg.Tags, rg.Tags)...)
+		diffs = append(diffs, diffMaps(name, "metric filter", lg.MetricFilters, rg.MetricFilters)...)
+		diffs = append(diffs, diffMaps(name, "subscription filter", lg.SubscriptionFilters, rg.SubscriptionFilters)...)
+	}
+	return diffs
+}
+
+func diff() {
+	leftTarget, rightTarget := cloudwatch.ParseTarget(*diffLeft), cloudwatch.ParseTarget(*diffRight)
+
+	left, err := cloudwatch.DescribeGroupsConfig(leftTarget, *diffPattern)
+	kingpin.FatalIfError(err, "cannot describe %s log groups", leftTarget)
+	right, err := cloudwatch.DescribeGroupsConfig(rightTarget, *diffPattern)
+	kingpin.FatalIfError(err, "cannot describe %s log groups", rightTarget)
+
+	diffs := diffGroups(left, right)
+	switch *diffOutput {
+	case "json":
+		if diffs == nil {
+			diffs = []difference{}
+		}
+		printJSON(diffs)
+	default:
+		if len(diffs) == 0 {
+			fmt.Println("No differences.")
+			return
+		}
+		var rows [][]string
+		for _, d := range diffs {
+			rows = append(rows, []string{d.Group, d.Field, d.Left, d.Right})
+		}
+		printTable([]string{"GROUP", "FIELD", leftTarget.String(), rightTarget.String()}, rows)
+	}
+	if len(diffs) > 0 {
+		os.Exit(1)
+	}
+}
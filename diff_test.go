@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffMaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		left     map[string]string
+		right    map[string]string
+		expected []difference
+	}{
+		{"equal", map[string]string{"env": "prod"}, map[string]string{"env": "prod"}, nil},
+		{"both empty", nil, nil, nil},
+		{"changed", map[string]string{"env": "prod"}, map[string]string{"env": "staging"},
+			[]difference{{"/ecs/api", "tag[env]", "prod", "staging"}}},
+		{"missing on the right", map[string]string{"env": "prod", "team": "core"}, map[string]string{"env": "prod"},
+			[]difference{{"/ecs/api", "tag[team]", "core", "-"}}},
+		{"missing on the left", nil, map[string]string{"team": "core"},
+			[]difference{{"/ecs/api", "tag[team]", "-", "core"}}},
+		{"sorted by key", map[string]string{"b": "1", "a": "1"}, map[string]string{"b": "2", "a": "2"},
+			[]difference{{"/ecs/api", "tag[a]", "1", "2"}, {"/ecs/api", "tag[b]", "1", "2"}}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, diffMaps("/ecs/api", "tag", tt.left, tt.right), tt.name)
+	}
+}
+
+func TestDiffGroups(t *testing.T) {
+	group := func(name string, retention int64, filters map[string]string) *cloudwatch.LogGroup {
+		return &cloudwatch.LogGroup{Name: name, RetentionInDays: retention, MetricFilters: filters}
+	}
+	errorsFilter := map[string]string{"errors": "ERROR"}
+
+	tests := []struct {
+		name     string
+		left     []*cloudwatch.LogGroup
+		right    []*cloudwatch.LogGroup
+		expected []difference
+	}{
+		{"equal",
+			[]*cloudwatch.LogGroup{group("/ecs/api", 30, errorsFilter)},
+			[]*cloudwatch.LogGroup{group("/ecs/api", 30, errorsFilter)},
+			nil},
+		{"missing on the right",
+			[]*cloudwatch.LogGroup{group("/ecs/api", 30, nil), group("/ecs/web", 30, nil)},
+			[]*cloudwatch.LogGroup{group("/ecs/api", 30, nil)},
+			[]difference{{"/ecs/web", "exists", "yes", "no"}}},
+		{"missing on the left",
+			[]*cloudwatch.LogGroup{group("/ecs/web", 30, nil)},
+			[]*cloudwatch.LogGroup{group("/ecs/api", 30, nil), group("/ecs/web", 30, nil)},
+			[]difference{{"/ecs/api", "exists", "no", "yes"}}},
+		{"changed retention",
+			[]*cloudwatch.LogGroup{group("/ecs/api", 30, nil)},
+			[]*cloudwatch.LogGroup{group("/ecs/api", 0, nil)},
+			[]difference{{"/ecs/api", "retention", "30d", "never expire"}}},
+		{"changed filter",
+			[]*cloudwatch.LogGroup{group("/ecs/api", 30, errorsFilter)},
+			[]*cloudwatch.LogGroup{group("/ecs/api", 30, map[string]string{"errors": "?ERROR ?FATAL"})},
+			[]difference{{"/ecs/api", "metric filter[errors]", "ERROR", "?ERROR ?FATAL"}}},
+		{"missing filter",
+			[]*cloudwatch.LogGroup{group("/ecs/api", 30, errorsFilter)},
+			[]*cloudwatch.LogGroup{group("/ecs/api", 30, nil)},
+			[]difference{{"/ecs/api", "metric filter[errors]", "ERROR", "-"}}},
+		{"sorted by group",
+			[]*cloudwatch.LogGroup{group("/ecs/web", 7, nil), group("/ecs/api", 7, nil)},
+			[]*cloudwatch.LogGroup{group("/ecs/api", 14, nil), group("/ecs/web", 14, nil)},
+			[]difference{{"/ecs/api", "retention", "7d", "14d"}, {"/ecs/web", "retention", "7d", "14d"}}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, diffGroups(tt.left, tt.right), tt.name)
+	}
+}
+
+func TestDiffGroupsEncryption(t *testing.T) {
+	//different keys in different accounts are not a difference
+	left := []*cloudwatch.LogGroup{{Name: "/ecs/api", KmsKeyID: "arn:aws:kms:eu-west-1:111111111111:key/a"}}
+	right := []*cloudwatch.LogGroup{{Name: "/ecs/api", KmsKeyID: "arn:aws:kms:eu-west-1:222222222222:key/b"}}
+	assert.Empty(t, diffGroups(left, right))
+
+	right[0].KmsKeyID = ""
+	assert.Equal(t, []difference{{"/ecs/api", "encryption", "kms", "none"}}, diffGroups(left, right))
+}
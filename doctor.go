@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	doctorCommand  = kingpin.Command("doctor", "Check the AWS configuration, credentials and permissions cw uses.")
+	doctorCommands = doctorCommand.Flag("commands", "Comma separated cw commands whose permissions are checked, e.g. tail,ls.").Default("ls,tail").String()
+	doctorGroup    = doctorCommand.Flag("group", "The log group the permissions are checked on, the first one of the account by default.").HintAction(groupsCompletion).String()
+)
+
+type doctorReport struct {
+	failed bool
+}
+
+func (r *doctorReport) ok(check string, value string) {
+	fmt.Printf("%s %-18s %s\n", color.GreenString("✔"), check, value)
+}
+
+func (r *doctorReport) warn(check string, value string, hint string) {
+	fmt.Printf("%s %-18s %s\n", color.YellowString("!"), check, value)
+	if hint != "" {
+		fmt.Printf("  %s %s\n", color.CyanString("hint:"), hint)
+	}
+}
+
+func (r *doctorReport) fail(check string, err error, hint string) {
+	r.failed = true
+	msg := err.Error()
+	if awsErr, ok := err.(awserr.Error); ok {
+		msg = fmt.Sprintf("%s: %s", awsErr.Code(), awsErr.Message())
+	}
+	fmt.Printf("%s %-18s %s\n", color.RedString("✘"), check, msg)
+	if hint != "" {
+		fmt.Printf("  %s %s\n", color.CyanString("hint:"), hint)
+	}
+}
+
+//commandsActions returns the CloudWatch Logs actions called by the commands
+func commandsActions(commands []string) ([]string, error) {
+	set := make(map[string]bool)
+	for _, c := range commands {
+		actions, ok := commandActions[c]
+		if !ok {
+			return nil, fmt.Errorf("unknown command %q, available commands: %s", c, strings.Join(commandNames(), ", "))
+		}
+		for _, a := range actions {
+			set[a] = true
+		}
+	}
+	var actions []string
+	for a := range set {
+		actions = append(actions, a)
+	}
+	sort.Strings(actions)
+	return actions, nil
+}
+
+//simulationResource returns the resource an action is simulated on, scoped as in the policies iam-policy prints
+func simulationResource(action string, region string, account string, group string) string {
+	switch {
+	case group == "" || accountLevelActions[action]:
+		return "*"
+	case streamLevelActions[action]:
+		return fmt.Sprintf("arn:aws:logs:%s:%s:log-group:%s:log-stream:cw-doctor", region, account, group)
+	}
+	return groupArn(region, account, group)
+}
+
+//deniedActions returns the actions of each command the simulation decisions don't allow
+func deniedActions(commands []string, decisions map[string]string) map[string][]string {
+	denied := make(map[string][]string)
+	for _, c := range commands {
+		for _, a := range commandActions[c] {
+			if decisions[a] != "allowed" {
+				denied[c] = append(denied[c], a)
+			}
+		}
+	}
+	return denied
+}
+
+func credentialsHint(err error) string {
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case "NoCredentialProviders":
+			return "run `aws configure` or export AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY."
+		case "ExpiredToken", "ExpiredTokenException":
+			return "the session token has expired, refresh the temporary credentials."
+		case "InvalidClientTokenId", "SignatureDoesNotMatch":
+			return "the access key is invalid, check ~/.aws/credentials and the AWS_* environment variables."
+		case "RequestError":
+			return "cannot reach AWS, check the network connection and the HTTPS_PROXY environment variable."
+		case "SharedConfigProfileNotExistsError":
+			return "the profile doesn't exist, check AWS_PROFILE and ~/.aws/config."
+		}
+	}
+	return ""
+}
+
+func doctor() {
+	target := cloudwatch.Target{}
+	r := &doctorReport{}
+	defer func() {
+		if r.failed {
+			os.Exit(1)
+		}
+	}()
+
+	commands := splitList(*doctorCommands)
+	actions, err := commandsActions(commands)
+	kingpin.FatalIfError(err, "")
+
+	env, err := cloudwatch.ResolveEnvironment(target)
+	switch {
+	case err == cloudwatch.ErrNoRegion:
+		r.ok("profile", env.Profile)
+		r.fail("region", err, fmt.Sprintf("set `region` for the %s profile in ~/.aws/config or export AWS_REGION.", env.Profile))
+		return
+	case err != nil && env.Region == "":
+		r.fail("profile", err, credentialsHint(err))
+		return
+	case err != nil:
+		r.ok("profile", env.Profile)
+		r.ok("region", env.Region)
+		r.fail("credentials", err, credentialsHint(err))
+		return
+	}
+	r.ok("profile", env.Profile)
+	r.ok("region", env.Region)
+	r.ok("endpoint", env.Endpoint)
+	r.ok("credentials", fmt.Sprintf("%s (%s)", env.CredentialSource, env.Expiry))
+
+	identity, err := cloudwatch.CallerIdentity(target)
+	if err != nil {
+		r.fail("caller identity", err, credentialsHint(err))
+		return
+	}
+	r.ok("account", identity.Account)
+	r.ok("caller identity", identity.Arn)
+
+	group := *doctorGroup
+	if group == "" {
+		//a failure shows as the DescribeLogGroups denial
+		group, _ = cloudwatch.SampleGroup(target)
+	}
+	if group != "" {
+		r.ok("log group", group)
+	}
+
+	//the actions are simulated on the resources iam-policy scopes them to, a simulation holds a single resource
+	resources := make(map[string][]string)
+	for _, a := range actions {
+		resource := simulationResource(a, env.Region, identity.Account, group)
+		resources[resource] = append(resources[resource], a)
+	}
+	decisions := make(map[string]string)
+	for resource, resourceActions := range resources {
+		var d map[string]string
+		if d, err = cloudwatch.SimulatePermissions(target, identity.Arn, resourceActions, resource); err != nil {
+			break
+		}
+		for a, decision := range d {
+			decisions[a] = decision
+		}
+	}
+	if err == nil {
+		denied := deniedActions(commands, decisions)
+		for _, c := range commands {
+			if len(denied[c]) == 0 {
+				r.ok(c, "allowed (policy simulation)")
+				continue
+			}
+			var details []string
+			for _, a := range denied[c] {
+				details = append(details, fmt.Sprintf("logs:%s %s", a, decisions[a]))
+			}
+			r.fail(c, fmt.Errorf("%s (policy simulation)", strings.Join(details, ", ")), fmt.Sprintf("`cw iam-policy --commands %s` prints the policy it needs.", c))
+		}
+		return
+	}
+	r.warn("policy simulation", "not available, testing the read only actions instead",
+		"grant iam:SimulatePrincipalPolicy to test the write actions as well.")
+
+	results, err := cloudwatch.ProbePermissions(target, actions, group)
+	if err != nil {
+		r.fail("permissions", err, "")
+		return
+	}
+	var untested []string
+	for _, a := range actions {
+		err, tested := results[a]
+		switch {
+		case !tested:
+			untested = append(untested, a)
+		case err == nil:
+			r.ok("logs:"+a, "allowed")
+		default:
+			r.fail("logs:"+a, err, "grant logs:"+a+", `cw iam-policy` prints the policy cw commands need.")
+		}
+	}
+	if len(untested) > 0 {
+		r.warn("not tested", strings.Join(untested, ", "), "")
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandsActions(t *testing.T) {
+	actions, err := commandsActions([]string{"ls", "tail"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DescribeLogGroups", "DescribeLogStreams", "FilterLogEvents"}, actions)
+
+	_, err = commandsActions([]string{"tial"})
+	assert.Error(t, err)
+}
+
+func TestSimulationResource(t *testing.T) {
+	assert.Equal(t, "*", simulationResource("DescribeLogGroups", "eu-west-1", "123456789012", "/ecs/api"))
+	assert.Equal(t, "arn:aws:logs:eu-west-1:123456789012:log-group:/ecs/api:*", simulationResource("FilterLogEvents", "eu-west-1", "123456789012", "/ecs/api"))
+	assert.Equal(t, "arn:aws:logs:eu-west-1:123456789012:log-group:/ecs/api:log-stream:cw-doctor", simulationResource("PutLogEvents", "eu-west-1", "123456789012", "/ecs/api"))
+	assert.Equal(t, "*", simulationResource("FilterLogEvents", "eu-west-1", "123456789012", ""))
+}
+
+func TestDeniedActions(t *testing.T) {
+	decisions := map[string]string{
+		"DescribeLogGroups":   "allowed",
+		"DescribeLogStreams":  "allowed",
+		"FilterLogEvents":     "allowed",
+		"CreateExportTask":    "implicitDeny",
+		"DescribeExportTasks": "allowed",
+	}
+	denied := deniedActions([]string{"tail", "export"}, decisions)
+	assert.Empty(t, denied["tail"])
+	assert.Equal(t, []string{"CreateExportTask"}, denied["export"])
+
+	//the actions missing from the simulation are denied
+	denied = deniedActions([]string{"diff"}, decisions)
+	assert.Equal(t, []string{"ListTagsLogGroup", "DescribeMetricFilters", "DescribeSubscriptionFilters"}, denied["diff"])
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	eksCluster    = tailCommand.Flag("eks", "Tail the control plane logs of an EKS cluster instead of a group.").PlaceHolder("CLUSTER").String()
+	eksComponents = tailCommand.Flag("component", "The control plane components tailed with --eks, comma separated: api, audit, authenticator, controllerManager, scheduler. All by default.").String()
+)
+
+//auditSummary is the one line view of a Kubernetes audit event
+type auditSummary struct {
+	User        string `json:"user"`
+	Verb        string `json:"verb"`
+	Resource    string `json:"resource"`
+	Namespace   string `json:"namespace,omitempty"`
+	Code        int    `json:"code"`
+	Subresource string `json:"-"`
+	Name        string `json:"-"`
+}
+
+func (a *auditSummary) String() string {
+	resource := a.Resource
+	if a.Name != "" {
+		resource += "/" + a.Name
+	}
+	if a.Subresource != "" {
+		resource += "/" + a.Subresource
+	}
+	s := fmt.Sprintf("%s %s %s", a.User, a.Verb, resource)
+	if a.Namespace != "" {
+		s += " -n " + a.Namespace
+	}
+	return fmt.Sprintf("%s %d", s, a.Code)
+}
+
+//parseAudit summarizes a Kubernetes audit event, nil if the message isn't one
+func parseAudit(message string) *auditSummary {
+	var event struct {
+		Kind       string `json:"kind"`
+		Verb       string `json:"verb"`
+		RequestURI string `json:"requestURI"`
+		User       struct {
+			Username string `json:"username"`
+		} `json:"user"`
+		ObjectRef *struct {
+			Resource    string `json:"resource"`
+			Subresource string `json:"subresource"`
+			Namespace   string `json:"namespace"`
+			Name        string `json:"name"`
+		} `json:"objectRef"`
+		ResponseStatus *struct {
+			Code int `json:"code"`
+		} `json:"responseStatus"`
+	}
+	if err := json.Unmarshal([]byte(message), &event); err != nil || event.Kind != "Event" {
+		return nil
+	}
+	a := &auditSummary{User: event.User.Username, Verb: event.Verb, Resource: event.RequestURI}
+	if r := event.ObjectRef; r != nil {
+		a.Resource, a.Subresource, a.Namespace, a.Name = r.Resource, r.Subresource, r.Namespace, r.Name
+	}
+	if event.ResponseStatus != nil {
+		a.Code = event.ResponseStatus.Code
+	}
+	return a
+}
+
+//eksTail points the tail to the group of the --eks cluster, it returns the components to tail
+func eksTail() map[string]bool {
+	if *logGroupName != "" {
+		kingpin.Fatalf("--eks replaces the group argument")
+	}
+	group, err := cloudwatch.EKSLogGroup(cloudwatch.Target{}, *eksCluster)
+	kingpin.FatalIfError(err, "")
+	*logGroupName = group
+
+	components := make(map[string]bool)
+	for _, c := range strings.Split(*eksComponents, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if _, ok := cloudwatch.EKSComponents[c]; !ok {
+			var valid []string
+			for c := range cloudwatch.EKSComponents {
+				valid = append(valid, c)
+			}
+			sort.Strings(valid)
+			kingpin.Fatalf("unknown component %s, valid components: %s", c, strings.Join(valid, ", "))
+		}
+		components[c] = true
+	}
+	if len(components) == 0 {
+		for c := range cloudwatch.EKSComponents {
+			components[c] = true
+		}
+	}
+	if len(components) == 1 {
+		for c := range components {
+			*logStreamName = cloudwatch.EKSComponents[c]
+		}
+	}
+	return components
+}
+
+//filterComponents keeps the events of the streams of the given control plane components
+func filterComponents(in <-chan *cloudwatchlogs.FilteredLogEvent, components map[string]bool) <-chan *cloudwatchlogs.FilteredLogEvent {
+	out := make(chan *cloudwatchlogs.FilteredLogEvent)
+	go func() {
+		defer close(out)
+		for event := range in {
+			if event.LogStreamName != nil && components[cloudwatch.EKSComponent(*event.LogStreamName)] {
+				out <- event
+			}
+		}
+	}()
+	return out
+}
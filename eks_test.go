@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAudit(t *testing.T) {
+	a := parseAudit(`{"kind":"Event","apiVersion":"audit.k8s.io/v1","verb":"get","requestURI":"/api/v1/namespaces/prod/pods/web-1/log",` +
+		`"user":{"username":"alice"},"objectRef":{"resource":"pods","namespace":"prod","name":"web-1","subresource":"log"},"responseStatus":{"code":200}}`)
+	assert.Equal(t, &auditSummary{User: "alice", Verb: "get", Resource: "pods", Namespace: "prod", Name: "web-1", Subresource: "log", Code: 200}, a)
+	assert.Equal(t, "alice get pods/web-1/log -n prod 200", a.String())
+}
+
+func TestParseAuditNonResource(t *testing.T) {
+	a := parseAudit(`{"kind":"Event","verb":"get","requestURI":"/healthz","user":{"username":"system:anonymous"}}`)
+	assert.Equal(t, &auditSummary{User: "system:anonymous", Verb: "get", Resource: "/healthz"}, a)
+	assert.Equal(t, "system:anonymous get /healthz 0", a.String())
+}
+
+func TestParseAuditNotAnEvent(t *testing.T) {
+	assert.Nil(t, parseAudit("I0701 10:00:00.000000 1 trace.go:116] Trace"))
+	assert.Nil(t, parseAudit(`{"kind":"EventList","items":[]}`))
+}
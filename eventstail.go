@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	eventsCommand = kingpin.Command("events", "Debug EventBridge rules.")
+	eventsTail    = eventsCommand.Command("tail", "Tail the events matching a pattern through a temporary rule and log group, deleted on exit.")
+	eventsBus     = eventsTail.Flag("bus", "The event bus.").Default("default").String()
+	eventsPattern = eventsTail.Flag("pattern", `The event pattern, e.g. '{"source":["aws.ecs"]}'.`).Required().String()
+)
+
+//eventsTailMaxAge is how long the sessions of other hosts are considered alive
+const eventsTailMaxAge = 24 * time.Hour
+
+//eventsTailOwner describes the session, saved in the rule description
+func eventsTailOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("cw events tail host=%s pid=%d started=%s", host, os.Getpid(), time.Now().UTC().Format(time.RFC3339))
+}
+
+//eventsTailAlive reports whether the session owning a rule could still be running
+//The process is checked for the sessions of this host, the others are alive until eventsTailMaxAge
+func eventsTailAlive(description string) bool {
+	owner := make(map[string]string)
+	for _, f := range strings.Fields(description) {
+		if kv := strings.SplitN(f, "=", 2); len(kv) == 2 {
+			owner[kv[0]] = kv[1]
+		}
+	}
+	started, err := time.Parse(time.RFC3339, owner["started"])
+	if err != nil {
+		return false
+	}
+	if host, _ := os.Hostname(); owner["host"] == host {
+		pid, err := strconv.Atoi(owner["pid"])
+		if err != nil {
+			return false
+		}
+		p, err := os.FindProcess(pid)
+		return err == nil && p.Signal(syscall.Signal(0)) == nil
+	}
+	return time.Since(started) < eventsTailMaxAge
+}
+
+func eventsTailCommand() {
+	if *eventsBus != "default" {
+		kingpin.Fatalf("only the default event bus is supported")
+	}
+	var pattern interface{}
+	kingpin.FatalIfError(json.Unmarshal([]byte(*eventsPattern), &pattern), "invalid event pattern")
+
+	target := cloudwatch.Target{}
+	g := newGuard("events tail", target)
+	if !g.allow("Create a temporary rule and log group for the events of bus", *eventsBus, *eventsBus, true) {
+		return
+	}
+
+	swept, err := cloudwatch.SweepEventsTails(target, eventsTailAlive)
+	for _, e := range swept {
+		fmt.Fprintf(os.Stderr, "Deleted the rule %s and the log group %s of a previous session\n", e.Rule, e.Group)
+	}
+	kingpin.FatalIfError(err, "cannot delete the resources of the previous sessions")
+
+	start := time.Now().UTC()
+	e, err := cloudwatch.StartEventsTail(target, *eventsPattern, eventsTailOwner())
+	stop := func() {
+		err := e.Stop(target)
+		g.record("delete rule", e.Rule, err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s cannot delete %s and %s, they'll be deleted by the next events tail: %s\n", color.RedString("Error:"), e.Rule, e.Group, err)
+			return
+		}
+		//the last session deletes the shared resource policy
+		if _, err := cloudwatch.SweepEventsTails(target, eventsTailAlive); err != nil {
+			fmt.Fprintf(os.Stderr, "%s cannot delete the resource policy of the sessions: %s\n", color.RedString("Error:"), err)
+		}
+	}
+	g.record("create rule", *eventsBus, err)
+	if err != nil {
+		if e != nil {
+			stop()
+		}
+		kingpin.FatalIfError(err, "cannot create the rule")
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		stop()
+		os.Exit(0)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Tailing the events of rule %s, Ctrl-C to stop...\n", e.Rule)
+	follow := true
+	all, grep := "*", ""
+	for event := range cloudwatch.Tail(&e.Group, &all, &follow, &start, &time.Time{}, &grep) {
+		fmt.Println(*event.Message)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var readOnly = kingpin.Flag("read-only", "Refuse to run any command changing AWS resources.").Bool()
+
+//guard protects the AWS resources of a target from unwanted changes
+//Changes are refused in --read-only mode and need a typed confirmation in protected environments
+type guard struct {
+	command   string
+	profile   string
+	account   string
+	protected bool
+}
+
+func newGuard(command string, target cloudwatch.Target) *guard {
+	if *readOnly {
+		kingpin.Fatalf("%s changes AWS resources and --read-only is set", command)
+	}
+	c, err := loadConfig()
+	kingpin.FatalIfError(err, "invalid cw configuration")
+
+	g := &guard{command: command, profile: cloudwatch.ResolveProfile(target)}
+	g.protected = contains(c.protectedProfiles, g.profile)
+	if len(c.protectedAccounts) > 0 {
+		identity, err := cloudwatch.CallerIdentity(target)
+		kingpin.FatalIfError(err, "cannot verify whether the account is protected")
+		g.account = identity.Account
+		g.protected = g.protected || contains(c.protectedAccounts, g.account)
+	}
+	return g
+}
+
+//allow asks for the confirmation of the action on the resource
+//In protected environments the name must be typed, otherwise a yes/no answer(skipped by assumeYes) is enough
+func (g *guard) allow(action string, resource string, name string, assumeYes bool) bool {
+	if !g.protected {
+		return assumeYes || confirm(fmt.Sprintf("%s %s?", action, resource))
+	}
+	env := g.profile
+	if g.account != "" {
+		env = fmt.Sprintf("%s, account %s", g.profile, g.account)
+	}
+	fmt.Printf("%s %s is a protected environment.\n", color.RedString("Warning:"), env)
+	return confirmTyped(fmt.Sprintf("%s %s?", action, resource), name)
+}
+
+type auditEntry struct {
+	Time     time.Time `json:"time"`
+	Profile  string    `json:"profile"`
+	Account  string    `json:"account,omitempty"`
+	Command  string    `json:"command"`
+	Action   string    `json:"action"`
+	Resource string    `json:"resource"`
+	Error    string    `json:"error,omitempty"`
+}
+
+//record appends the outcome of the action to the audit journal(~/.cw/audit.log) in protected environments
+func (g *guard) record(action string, resource string, actionErr error) {
+	if !g.protected {
+		return
+	}
+	e := auditEntry{Time: time.Now().UTC(), Profile: g.profile, Account: g.account, Command: g.command, Action: action, Resource: resource}
+	if actionErr != nil {
+		e.Error = actionErr.Error()
+	}
+	p, err := cwPath("audit.log")
+	if err == nil {
+		var f *os.File
+		f, err = os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err == nil {
+			defer f.Close()
+			err = json.NewEncoder(f).Encode(e)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s cannot write the audit journal: %s\n", color.RedString("Warning:"), err)
+	}
+}
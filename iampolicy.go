@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	iamPolicyCommand  = kingpin.Command("iam-policy", "Print the least privilege IAM policy needed to run cw commands.")
+	iamPolicyCommands = iamPolicyCommand.Flag("commands", "Comma separated cw commands, e.g. tail,ls.").Default("ls,tail").String()
+	iamPolicyGroups   = iamPolicyCommand.Flag("groups", "Comma separated log group patterns the policy is scoped to.").Default("*").String()
+	iamPolicyRegion   = iamPolicyCommand.Flag("region", "The region of the log groups.").Default("*").String()
+	iamPolicyAccount  = iamPolicyCommand.Flag("account", "The account ID of the log groups.").Default("*").String()
+)
+
+//commandActions lists the CloudWatch Logs actions each cw command calls
+//
+//export isn't a command, it grants the export tasks archive runs without the deletion of the group
+var commandActions = map[string][]string{
+	"ls":            {"DescribeLogGroups", "DescribeLogStreams"},
+	"tail":          {"DescribeLogGroups", "DescribeLogStreams", "FilterLogEvents"},
+	"diff":          {"DescribeLogGroups", "ListTagsLogGroup", "DescribeMetricFilters", "DescribeSubscriptionFilters"},
+	"usage":         {"DescribeLogGroups", "FilterLogEvents"},
+	"cost-drivers":  {"FilterLogEvents"},
+	"export":        {"CreateExportTask", "DescribeExportTasks"},
+	"archive":       {"DescribeLogGroups", "DescribeLogStreams", "CreateExportTask", "DescribeExportTasks", "DeleteLogGroup"},
+	"loadgen":       {"CreateLogGroup", "CreateLogStream", "DescribeLogStreams", "PutLogEvents"},
+	"lint":          {"DescribeLogGroups", "ListTagsLogGroup", "DescribeMetricFilters", "DescribeSubscriptionFilters"},
+	"undo":          {"DescribeLogGroups", "ListTagsLogGroup", "DescribeMetricFilters", "DescribeSubscriptionFilters", "PutRetentionPolicy", "DeleteRetentionPolicy", "TagLogGroup", "UntagLogGroup", "AssociateKmsKey", "DisassociateKmsKey", "PutMetricFilter", "DeleteMetricFilter", "PutSubscriptionFilter", "DeleteSubscriptionFilter"},
+	"doctor":        {"DescribeLogGroups", "DescribeLogStreams", "FilterLogEvents", "DescribeExportTasks", "ListTagsLogGroup", "DescribeMetricFilters", "DescribeSubscriptionFilters"},
+	"athena":        {},
+	"events":        {"CreateLogGroup", "PutRetentionPolicy", "DescribeLogGroups", "PutResourcePolicy", "DeleteResourcePolicy", "DescribeLogStreams", "FilterLogEvents", "DeleteLogGroup"},
+	"metrics":       {"DescribeMetricFilters"},
+	"alarm":         {"DescribeMetricFilters", "PutMetricFilter"},
+	"org":           {"DescribeLogGroups"},
+	"search":        {"DescribeLogGroups", "FilterLogEvents"},
+	"sfn":           {"DescribeLogGroups", "FilterLogEvents"},
+	"otlp-receiver": {"CreateLogGroup", "CreateLogStream", "DescribeLogStreams", "PutLogEvents"},
+	"pipeline":      {"CreateLogGroup", "CreateLogStream", "DescribeLogStreams", "PutLogEvents", "DeleteLogStream", "DescribeSubscriptionFilters", "PutSubscriptionFilter", "DeleteSubscriptionFilter"},
+}
+
+//commandExtraPermissions lists the permissions on other services some commands need
+var commandExtraPermissions = map[string][]string{
+	"archive":  {"s3:ListBucket", "s3:GetObject", "s3:PutObject"},
+	"tail":     {"kinesis:ListShards", "kinesis:GetShardIterator", "kinesis:GetRecords (with --kinesis)", "s3:ListBucket", "s3:GetObject (with --s3)", "cloudwatch:DescribeAlarmHistory (with --with-alarms)", "ec2:DescribeInstances (with --enrich)", "eks:DescribeCluster (with --eks)"},
+	"doctor":   {"iam:SimulatePrincipalPolicy"},
+	"pipeline": {"iam:CreateRole", "iam:GetRole", "iam:PutRolePolicy", "iam:PassRole", "iam:ListRolePolicies", "iam:DeleteRolePolicy", "iam:DeleteRole", "firehose:CreateDeliveryStream", "firehose:DescribeDeliveryStream", "firehose:DeleteDeliveryStream", "s3:ListBucket", "s3:GetObject"},
+	"events":   {"events:PutRule", "events:PutTargets", "events:ListRules", "events:RemoveTargets", "events:DeleteRule"},
+	"metrics":  {"cloudwatch:GetMetricStatistics (with chart)"},
+	"alarm":    {"cloudwatch:DescribeAlarms", "cloudwatch:PutMetricAlarm", "cloudwatch:DescribeAlarmsForMetric"},
+	"undo":     {"cloudwatch:DescribeAlarms", "cloudwatch:PutMetricAlarm", "cloudwatch:DeleteAlarms"},
+	"org":      {"organizations:ListAccounts", "sts:AssumeRole on the --role of every account"},
+	"sfn":      {"states:ListStateMachines", "states:DescribeStateMachine"},
+	"athena":   {"s3:ListBucket", "athena:StartQueryExecution", "athena:GetQueryExecution", "athena:GetQueryResults", "glue:GetTable", "s3:GetObject", "s3:PutObject"},
+}
+
+//accountLevelActions don't support resource level permissions and must be granted on all resources
+var accountLevelActions = map[string]bool{
+	"DescribeLogGroups":        true,
+	"DescribeExportTasks":      true,
+	"DescribeResourcePolicies": true,
+	"PutResourcePolicy":        true,
+	"DeleteResourcePolicy":     true,
+}
+
+//streamLevelActions are authorized against the log stream ARNs
+var streamLevelActions = map[string]bool{
+	"CreateLogStream": true,
+	"PutLogEvents":    true,
+	"GetLogEvents":    true,
+}
+
+type policyStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+func commandNames() []string {
+	var names []string
+	for name := range commandActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//groupArn returns the ARN of the log groups matching the pattern
+//Log group ARNs end with :* when returned by the API, a trailing wildcard matches them too
+func groupArn(region string, account string, pattern string) string {
+	if !strings.HasSuffix(pattern, "*") {
+		pattern += ":*"
+	}
+	return fmt.Sprintf("arn:aws:logs:%s:%s:log-group:%s", region, account, pattern)
+}
+
+//leastPrivilegePolicy returns the policy allowing the given commands on the log groups matching the patterns
+func leastPrivilegePolicy(commands []string, patterns []string, region string, account string) (*policyDocument, error) {
+	global, groupLevel, streamLevel := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, c := range commands {
+		actions, ok := commandActions[c]
+		if !ok {
+			return nil, fmt.Errorf("unknown command %q, available commands: %s", c, strings.Join(commandNames(), ", "))
+		}
+		for _, a := range actions {
+			switch {
+			case accountLevelActions[a]:
+				global["logs:"+a] = true
+			case streamLevelActions[a]:
+				streamLevel["logs:"+a] = true
+			default:
+				groupLevel["logs:"+a] = true
+			}
+		}
+	}
+
+	var groupArns, streamArns []string
+	for _, p := range patterns {
+		groupArns = append(groupArns, groupArn(region, account, p))
+		streamArns = append(streamArns, fmt.Sprintf("arn:aws:logs:%s:%s:log-group:%s:log-stream:*", region, account, p))
+	}
+
+	keys := func(m map[string]bool) []string {
+		var k []string
+		for a := range m {
+			k = append(k, a)
+		}
+		sort.Strings(k)
+		return k
+	}
+	policy := &policyDocument{Version: "2012-10-17"}
+	if len(global) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{"CwAccountLevel", "Allow", keys(global), []string{"*"}})
+	}
+	if len(groupLevel) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{"CwLogGroups", "Allow", keys(groupLevel), groupArns})
+	}
+	if len(streamLevel) > 0 {
+		policy.Statement = append(policy.Statement, policyStatement{"CwLogStreams", "Allow", keys(streamLevel), streamArns})
+	}
+	return policy, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func iamPolicy() {
+	commands := splitList(*iamPolicyCommands)
+	policy, err := leastPrivilegePolicy(commands, splitList(*iamPolicyGroups), *iamPolicyRegion, *iamPolicyAccount)
+	kingpin.FatalIfError(err, "")
+	printJSON(policy)
+	for _, c := range commands {
+		if extra, ok := commandExtraPermissions[c]; ok {
+			fmt.Fprintf(os.Stderr, "%s %s also needs %s on its target resources\n", color.YellowString("Note:"), c, strings.Join(extra, ", "))
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeastPrivilegePolicy(t *testing.T) {
+	policy, err := leastPrivilegePolicy([]string{"tail", "ls", "export"}, []string{"/ecs/prod/*"}, "eu-west-1", "123456789012")
+	assert.NoError(t, err)
+	assert.Equal(t, []policyStatement{
+		{"CwAccountLevel", "Allow", []string{"logs:DescribeExportTasks", "logs:DescribeLogGroups"}, []string{"*"}},
+		{"CwLogGroups", "Allow", []string{"logs:CreateExportTask", "logs:DescribeLogStreams", "logs:FilterLogEvents"}, []string{"arn:aws:logs:eu-west-1:123456789012:log-group:/ecs/prod/*"}},
+	}, policy.Statement)
+}
+
+func TestLeastPrivilegePolicyUnknownCommand(t *testing.T) {
+	_, err := leastPrivilegePolicy([]string{"tial"}, []string{"*"}, "*", "*")
+	assert.Error(t, err)
+}
+
+func TestGroupArn(t *testing.T) {
+	assert.Equal(t, "arn:aws:logs:*:*:log-group:/ecs/api:*", groupArn("*", "*", "/ecs/api"))
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	undoCommand = kingpin.Command("undo", "Restore the configuration a cw command changed.")
+	undoID      = undoCommand.Arg("id", "The journal entry to undo, the latest one by default.").Int()
+	undoList    = undoCommand.Flag("list", "List the journal entries.").Short('l').Bool()
+	undoForce   = undoCommand.Flag("force", "Undo even if the configuration has changed since.").Bool()
+	undoYes     = undoCommand.Flag("yes", "Don't ask for confirmation, unless the environment is protected.").Bool()
+)
+
+//journalEntry is a configuration change recorded in ~/.cw/journal.jsonl
+type journalEntry struct {
+	ID      int                `json:"id"`
+	Time    time.Time          `json:"time"`
+	Profile string             `json:"profile"`
+	Region  string             `json:"region"`
+	Command string             `json:"command"`
+	Change  *cloudwatch.Change `json:"change"`
+	UndoOf  int                `json:"undoOf,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+func (e *journalEntry) target() cloudwatch.Target {
+	return cloudwatch.Target{Profile: e.Profile, Region: e.Region}
+}
+
+func readJournal() ([]*journalEntry, error) {
+	p, err := cwPath("journal.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*journalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("corrupted journal %s: %s", p, err)
+		}
+		entries = append(entries, &e)
+	}
+	return entries, scanner.Err()
+}
+
+func appendJournal(e *journalEntry) error {
+	entries, err := readJournal()
+	if err != nil {
+		return err
+	}
+	e.ID = 1
+	if len(entries) > 0 {
+		e.ID = entries[len(entries)-1].ID + 1
+	}
+	p, err := cwPath("journal.jsonl")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(e)
+}
+
+//applyChange changes a log group setting, recording the change in the journal and, in protected environments, in the audit journal
+func applyChange(g *guard, target cloudwatch.Target, kind string, group string, name string, after cloudwatch.State) error {
+	return recordChange(g, target, 0, kind, group, name, after)
+}
+
+//applyChangeRetrying is applyChange retrying the change while retryable(err) until the deadline
+//Only the outcome is journaled, not every attempt
+func applyChangeRetrying(g *guard, target cloudwatch.Target, kind string, group string, name string, after cloudwatch.State, retryable func(error) bool, deadline time.Time) error {
+	c, err := cloudwatch.ApplyChangeRetrying(target, kind, group, name, after, retryable, 10*time.Second, deadline)
+	return journalChange(g, target, 0, kind, group, c, err)
+}
+
+func recordChange(g *guard, target cloudwatch.Target, undoOf int, kind string, group string, name string, after cloudwatch.State) error {
+	c, err := cloudwatch.ApplyChange(target, kind, group, name, after)
+	return journalChange(g, target, undoOf, kind, group, c, err)
+}
+
+func journalChange(g *guard, target cloudwatch.Target, undoOf int, kind string, group string, c *cloudwatch.Change, err error) error {
+	action := kind
+	if undoOf > 0 {
+		action = "undo " + kind
+	}
+	g.record(action, group, err)
+	if c == nil {
+		return err
+	}
+
+	region, _ := cloudwatch.ResolveRegion(target)
+	e := &journalEntry{Time: time.Now().UTC(), Profile: cloudwatch.ResolveProfile(target), Region: region, Command: g.command, Change: c, UndoOf: undoOf}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if jErr := appendJournal(e); jErr != nil {
+		fmt.Fprintf(os.Stderr, "%s cannot write the journal: %s\n", color.RedString("Warning:"), jErr)
+	}
+	return err
+}
+
+func printJournal(entries []*journalEntry, undone map[int]int) {
+	var rows [][]string
+	for _, e := range entries {
+		status := ""
+		switch {
+		case e.Error != "":
+			status = color.RedString("failed")
+		case undone[e.ID] > 0:
+			status = fmt.Sprintf("undone by %d", undone[e.ID])
+		case e.UndoOf > 0:
+			status = fmt.Sprintf("undo of %d", e.UndoOf)
+		}
+		rows = append(rows, []string{strconv.Itoa(e.ID), e.Time.Format(time.RFC3339), e.Profile + ":" + e.Region, e.Command, e.Change.String(), status})
+	}
+	printTable([]string{"ID", "TIME", "TARGET", "COMMAND", "CHANGE", "STATUS"}, rows)
+}
+
+//undoCandidate returns the entry with the given ID or, when 0, the newest change that can be undone
+//Undo entries are skipped so that undoing again walks further back instead of redoing the change
+func undoCandidate(entries []*journalEntry, undone map[int]int, id int) *journalEntry {
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if id != 0 {
+			if e.ID == id {
+				return e
+			}
+			continue
+		}
+		if e.UndoOf == 0 && undone[e.ID] == 0 && e.Error == "" && !e.Change.IsDeletion() {
+			return e
+		}
+	}
+	return nil
+}
+
+func undo() {
+	entries, err := readJournal()
+	kingpin.FatalIfError(err, "")
+	undone := make(map[int]int)
+	for _, e := range entries {
+		if e.UndoOf > 0 && e.Error == "" {
+			undone[e.UndoOf] = e.ID
+		}
+	}
+	if *undoList {
+		printJournal(entries, undone)
+		return
+	}
+
+	entry := undoCandidate(entries, undone, *undoID)
+	switch {
+	case entry == nil:
+		kingpin.Fatalf("nothing to undo")
+	case entry.Error != "":
+		kingpin.Fatalf("change %d failed, there's nothing to undo", entry.ID)
+	case undone[entry.ID] > 0:
+		kingpin.Fatalf("change %d has already been undone by %d", entry.ID, undone[entry.ID])
+	case entry.Change.IsDeletion():
+		kingpin.Fatalf("change %d created or deleted a log group, deleted data cannot be recovered", entry.ID)
+	}
+
+	c := entry.Change
+	target := entry.target()
+	current, err := cloudwatch.CurrentState(target, c.Kind, c.Group, c.Name)
+	kingpin.FatalIfError(err, "cannot read the current configuration")
+	if !*undoForce && !reflect.DeepEqual(current, c.After) {
+		kingpin.Fatalf("the %s of %s has changed since change %d(now %s), use --force to restore it anyway", c.Kind, c.Group, entry.ID, current)
+	}
+
+	g := newGuard("undo", target)
+	if !g.allow("Restore", fmt.Sprintf("the %s of %s to %s", c.Kind, c.Group, c.Before), c.Group, *undoYes) {
+		return
+	}
+	kingpin.FatalIfError(recordChange(g, target, entry.ID, c.Kind, c.Group, c.Name, c.Before), "cannot undo change %d", entry.ID)
+	fmt.Printf("%s change %d undone.\n", color.GreenString("Done."), entry.ID)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/stretchr/testify/assert"
+)
+
+func journal() []*journalEntry {
+	return []*journalEntry{
+		{ID: 1, Change: &cloudwatch.Change{Kind: cloudwatch.RetentionChange, Group: "/a"}},
+		{ID: 2, Change: &cloudwatch.Change{Kind: cloudwatch.TagChange, Group: "/a"}},
+		{ID: 3, Change: &cloudwatch.Change{Kind: cloudwatch.GroupChange, Group: "/b"}},
+		{ID: 4, Change: &cloudwatch.Change{Kind: cloudwatch.KmsChange, Group: "/a"}, Error: "AccessDenied"},
+	}
+}
+
+func TestUndoCandidateSkipsDeletionsAndFailures(t *testing.T) {
+	assert.Equal(t, 2, undoCandidate(journal(), map[int]int{}, 0).ID)
+}
+
+func TestUndoCandidateWalksBack(t *testing.T) {
+	entries := append(journal(), &journalEntry{ID: 5, Change: &cloudwatch.Change{Kind: cloudwatch.TagChange, Group: "/a"}, UndoOf: 2})
+	undone := map[int]int{2: 5}
+	assert.Equal(t, 1, undoCandidate(entries, undone, 0).ID)
+
+	entries = append(entries, &journalEntry{ID: 6, Change: &cloudwatch.Change{Kind: cloudwatch.RetentionChange, Group: "/a"}, UndoOf: 1})
+	undone[1] = 6
+	assert.Nil(t, undoCandidate(entries, undone, 0))
+}
+
+func TestUndoCandidateByID(t *testing.T) {
+	assert.Equal(t, 3, undoCandidate(journal(), map[int]int{}, 3).ID)
+	assert.Nil(t, undoCandidate(journal(), map[int]int{}, 9))
+}
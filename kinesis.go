@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+//checkpointPath returns the file storing the shards checkpoints of a stream for a log group
+func checkpointPath(stream string, group string) (string, error) {
+	return cwPath("kinesis", stream+"_"+strings.Trim(strings.Replace(group, "/", "_", -1), "_")+".json")
+}
+
+func loadCheckpoints(p string) (map[string]string, error) {
+	checkpoints := make(map[string]string)
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return checkpoints, json.Unmarshal(b, &checkpoints)
+}
+
+//tailKinesis tails the events delivered to the --kinesis stream
+func tailKinesis(startTime time.Time, endTime time.Time) <-chan *cloudwatchlogs.FilteredLogEvent {
+	p, err := checkpointPath(*kinesisStream, *logGroupName)
+	kingpin.FatalIfError(err, "")
+	checkpoints := make(map[string]string)
+	if !*resetCheckpoint {
+		checkpoints, err = loadCheckpoints(p)
+		kingpin.FatalIfError(err, "cannot read the checkpoints %s", p)
+	}
+
+	var mu sync.Mutex
+	saved := make(map[string]string)
+	for shard, seq := range checkpoints {
+		saved[shard] = seq
+	}
+	t := &cloudwatch.KinesisTail{
+		Stream:        *kinesisStream,
+		LogGroupName:  *logGroupName,
+		LogStreamName: *logStreamName,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		Follow:        *follow,
+		Checkpoints:   checkpoints,
+		OnCheckpoint: func(shardID string, sequenceNumber string) {
+			mu.Lock()
+			defer mu.Unlock()
+			saved[shardID] = sequenceNumber
+			b, _ := json.Marshal(saved)
+			if err := ioutil.WriteFile(p, b, 0600); err != nil {
+				fmt.Fprintf(os.Stderr, "%s cannot save the checkpoints: %s\n", color.RedString("Warning:"), err)
+			}
+		},
+	}
+
+	return filterEvents(cloudwatch.TailKinesis(cloudwatch.Target{}, t), endTime)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpoints(t *testing.T) {
+	defer withHome(t)()
+
+	p, err := checkpointPath("subscription", "/ecs/api")
+	assert.NoError(t, err)
+	assert.Equal(t, "subscription_ecs_api.json", filepath.Base(p))
+
+	checkpoints, err := loadCheckpoints(p)
+	assert.NoError(t, err)
+	assert.Empty(t, checkpoints)
+
+	assert.NoError(t, ioutil.WriteFile(p, []byte(`{"shardId-000000000000":"4959"}`), 0600))
+	checkpoints, err = loadCheckpoints(p)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"shardId-000000000000": "4959"}, checkpoints)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/lucagrulla/cw/yamlutil"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	lintCommand = kingpin.Command("lint", "Check the log groups against governance rules.")
+	lintRules   = lintCommand.Flag("rules", "The YAML rules file.").Required().ExistingFile()
+	lintFailOn  = lintCommand.Flag("fail-on", "Exit with code 1 when there are violations of this severity or higher.").Default("warning").Enum("info", "warning", "error")
+	lintOutput  = lintCommand.Flag("output", "Output format.").Short('o').Default("table").Enum("table", "json")
+)
+
+var severities = map[string]int{"info": 0, "warning": 1, "error": 2}
+
+//lintRule is a rule of the rules file, e.g.
+//
+//	rules:
+//	  - name: prod-encryption
+//	    severity: error
+//	    match: /prod/*
+//	    require-kms: true
+type lintRule struct {
+	name         string
+	severity     string
+	match        string
+	exclude      string
+	retentionMax int64
+	requireTags  []string
+	requireKMS   bool
+	namePattern  *regexp.Regexp
+}
+
+type violation struct {
+	Group    string `json:"group"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func parseLintRules(doc string) ([]*lintRule, error) {
+	v, err := yamlutil.Parse(doc)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a rules list")
+	}
+	items, ok := root["rules"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a rules list")
+	}
+
+	var rules []*lintRule
+	for i, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("rule %d: expected a mapping", i+1)
+		}
+		r := &lintRule{name: fmt.Sprintf("rule-%d", i+1), severity: "error"}
+		//the name labels the errors of the other keys
+		if name, ok := fields["name"].(string); ok {
+			r.name = name
+		}
+		var keys []string
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := fields[key]
+			s, _ := value.(string)
+			switch key {
+			case "name":
+			case "severity":
+				if _, ok := severities[s]; !ok {
+					return nil, fmt.Errorf("rule %s: unknown severity %q", r.name, s)
+				}
+				r.severity = s
+			case "match":
+				r.match = s
+			case "exclude":
+				r.exclude = s
+			case "retention-max":
+				r.retentionMax, err = strconv.ParseInt(s, 10, 64)
+				if err != nil || r.retentionMax <= 0 {
+					return nil, fmt.Errorf("rule %s: retention-max must be a number of days", r.name)
+				}
+			case "require-tags":
+				tags, ok := value.([]interface{})
+				if !ok {
+					return nil, fmt.Errorf("rule %s: require-tags must be a list", r.name)
+				}
+				for _, t := range tags {
+					tag, _ := t.(string)
+					r.requireTags = append(r.requireTags, tag)
+				}
+			case "require-kms":
+				r.requireKMS, err = strconv.ParseBool(s)
+				if err != nil {
+					return nil, fmt.Errorf("rule %s: require-kms must be true or false", r.name)
+				}
+			case "name-pattern":
+				r.namePattern, err = regexp.Compile(s)
+				if err != nil {
+					return nil, fmt.Errorf("rule %s: invalid name-pattern: %s", r.name, err)
+				}
+			default:
+				return nil, fmt.Errorf("rule %d: unknown key %q", i+1, key)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (r *lintRule) check(g *cloudwatch.LogGroup) []string {
+	if !cloudwatch.MatchGroup(r.match, g.Name) || (r.exclude != "" && cloudwatch.MatchGroup(r.exclude, g.Name)) {
+		return nil
+	}
+	var failures []string
+	if r.retentionMax > 0 && (g.RetentionInDays == 0 || g.RetentionInDays > r.retentionMax) {
+		failures = append(failures, fmt.Sprintf("retention is %s, at most %dd allowed", formatRetention(g.RetentionInDays), r.retentionMax))
+	}
+	for _, tag := range r.requireTags {
+		if _, ok := g.Tags[tag]; !ok {
+			failures = append(failures, fmt.Sprintf("missing %q tag", tag))
+		}
+	}
+	if r.requireKMS && g.KmsKeyID == "" {
+		failures = append(failures, "not encrypted with a KMS key")
+	}
+	if r.namePattern != nil && !r.namePattern.MatchString(g.Name) {
+		failures = append(failures, fmt.Sprintf("name doesn't match %s", r.namePattern))
+	}
+	return failures
+}
+
+func lint() {
+	doc, err := ioutil.ReadFile(*lintRules)
+	kingpin.FatalIfError(err, "")
+	rules, err := parseLintRules(string(doc))
+	kingpin.FatalIfError(err, "invalid rules file %s", *lintRules)
+
+	describe := cloudwatch.DescribeGroups
+	for _, r := range rules {
+		if len(r.requireTags) > 0 {
+			describe = cloudwatch.DescribeGroupsConfig
+		}
+	}
+	groups, err := describe(cloudwatch.Target{}, "")
+	kingpin.FatalIfError(err, "cannot describe log groups")
+
+	var violations []violation
+	for _, g := range groups {
+		for _, r := range rules {
+			for _, msg := range r.check(g) {
+				violations = append(violations, violation{g.Name, r.name, r.severity, msg})
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return severities[violations[i].Severity] > severities[violations[j].Severity]
+	})
+
+	failed := false
+	for _, v := range violations {
+		if severities[v.Severity] >= severities[*lintFailOn] {
+			failed = true
+		}
+	}
+
+	if *lintOutput == "json" {
+		if violations == nil {
+			violations = []violation{}
+		}
+		printJSON(violations)
+	} else if len(violations) == 0 {
+		fmt.Printf("%s %d groups checked against %d rules.\n", color.GreenString("No violations."), len(groups), len(rules))
+	} else {
+		var rows [][]string
+		for _, v := range violations {
+			severity := v.Severity
+			switch severity {
+			case "error":
+				severity = color.RedString(severity)
+			case "warning":
+				severity = color.YellowString(severity)
+			}
+			rows = append(rows, []string{severity, v.Group, v.Rule, v.Message})
+		}
+		printTable([]string{"SEVERITY", "GROUP", "RULE", "MESSAGE"}, rows)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLintRules(t *testing.T) {
+	rules, err := parseLintRules(`
+rules:
+  - name: prod-encryption
+    match: /prod/*
+    require-kms: true
+    require-tags: [team, cost-center]
+  - match: /dev/*
+    severity: warning
+    retention-max: 14
+`)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, "prod-encryption", rules[0].name)
+	assert.True(t, rules[0].requireKMS)
+	assert.Equal(t, []string{"team", "cost-center"}, rules[0].requireTags)
+	assert.Equal(t, "rule-2", rules[1].name)
+	assert.Equal(t, int64(14), rules[1].retentionMax)
+}
+
+func TestParseLintRulesErrorsNameTheRule(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		_, err := parseLintRules("rules:\n  - severity: fatal\n    match: /prod/*\n    name: prod\n")
+		assert.EqualError(t, err, `rule prod: unknown severity "fatal"`)
+	}
+}
+
+func TestLintRuleCheck(t *testing.T) {
+	r := &lintRule{name: "r", match: "/prod/*", retentionMax: 30, requireTags: []string{"team"}, requireKMS: true}
+	failures := r.check(&cloudwatch.LogGroup{Name: "/prod/api", RetentionInDays: 90, Tags: map[string]string{}})
+	assert.Len(t, failures, 3)
+	assert.Empty(t, r.check(&cloudwatch.LogGroup{Name: "/dev/api"}))
+}
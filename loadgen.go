@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	loadgenCommand  = kingpin.Command("loadgen", "Write synthetic events to a log group.")
+	loadgenGroup    = loadgenCommand.Arg("group", "The log group name, created if missing.").Required().HintAction(groupsCompletion).String()
+	loadgenStreams  = loadgenCommand.Flag("streams", "Number of log streams to write to.").Default("1").Int()
+	loadgenRate     = loadgenCommand.Flag("rate", "Total events rate, e.g. 500/s or 1000/m.").Default("10/s").String()
+	loadgenTemplate = loadgenCommand.Flag("template", "Events template: json, apache or the path of a Go text/template file.").Default("json").String()
+	loadgenDuration = loadgenCommand.Flag("duration", "How long to write for.").Default("1m").Duration()
+)
+
+//loadgenTick is how often each stream writes a batch, PutLogEvents accepts 5 reqs/sec per stream
+const loadgenTick = 250 * time.Millisecond
+
+var loadgenTemplates = map[string]string{
+	"json":   `{"timestamp":"{{now}}","level":"{{pick "INFO" "INFO" "INFO" "WARN" "ERROR"}}","requestId":"{{uuid}}","method":"{{method}}","path":"{{path}}","status":{{status}},"latencyMs":{{int 1 900}},"stream":"{{stream}}","seq":{{seq}}}`,
+	"apache": `{{ip}} - - [{{clf}}] "{{method}} {{path}} HTTP/1.1" {{status}} {{int 200 50000}}`,
+}
+
+func loadgenFuncs(stream string, seq *int64) template.FuncMap {
+	return template.FuncMap{
+		"now":  func() string { return time.Now().UTC().Format(time.RFC3339Nano) },
+		"clf":  func() string { return time.Now().UTC().Format("02/Jan/2006:15:04:05 -0700") },
+		"int":  func(min int, max int) int { return min + rand.Intn(max-min+1) },
+		"pick": func(values ...string) string { return values[rand.Intn(len(values))] },
+		"ip":   func() string { return fmt.Sprintf("10.%d.%d.%d", rand.Intn(256), rand.Intn(256), rand.Intn(256)) },
+		"uuid": func() string {
+			return fmt.Sprintf("%08x-%04x-4%03x-%04x-%012x", rand.Uint32(), rand.Intn(1<<16), rand.Intn(1<<12), 0x8000|rand.Intn(1<<14), rand.Int63n(1<<48))
+		},
+		"method": func() string { return []string{"GET", "GET", "GET", "POST", "PUT", "DELETE"}[rand.Intn(6)] },
+		"path":   func() string { return []string{"/", "/login", "/api/users", "/api/orders", "/health"}[rand.Intn(5)] },
+		"status": func() int { return []int{200, 200, 200, 200, 201, 301, 404, 500}[rand.Intn(8)] },
+		"stream": func() string { return stream },
+		"seq":    func() int64 { return *seq },
+	}
+}
+
+func parseLoadgenTemplate(name string, stream string, seq *int64) (*template.Template, error) {
+	t := template.New(stream).Funcs(loadgenFuncs(stream, seq))
+	if text, ok := loadgenTemplates[name]; ok {
+		return t.Parse(text)
+	}
+	text, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.Parse(string(text))
+}
+
+//parseRate parses a rate like 500/s into events per second
+func parseRate(rate string) (float64, error) {
+	tokens := strings.SplitN(rate, "/", 2)
+	n, err := strconv.ParseFloat(tokens[0], 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid rate %q, expected e.g. 500/s", rate)
+	}
+	if len(tokens) == 1 {
+		return n, nil
+	}
+	switch tokens[1] {
+	case "s":
+		return n, nil
+	case "m":
+		return n / 60, nil
+	case "h":
+		return n / 3600, nil
+	}
+	return 0, fmt.Errorf("invalid rate %q, the unit must be s, m or h", rate)
+}
+
+type loadgenStats struct {
+	events int64
+	bytes  int64
+	errors int64
+}
+
+//loadgenStream writes events to a stream at the given rate until the deadline
+func loadgenStream(w *cloudwatch.StreamWriter, t *template.Template, seq *int64, rate float64, deadline time.Time, stats *loadgenStats) {
+	ticker := time.NewTicker(loadgenTick)
+	defer ticker.Stop()
+	var due float64
+	for now := range ticker.C {
+		if now.After(deadline) {
+			return
+		}
+		due += rate * loadgenTick.Seconds()
+		var batch []*cloudwatchlogs.InputLogEvent
+		var batchBytes int
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			if err := w.Put(batch); err != nil {
+				atomic.AddInt64(&stats.errors, 1)
+				fmt.Println(color.RedString(err.Error()))
+			} else {
+				atomic.AddInt64(&stats.events, int64(len(batch)))
+				atomic.AddInt64(&stats.bytes, int64(batchBytes))
+			}
+			batch, batchBytes = nil, 0
+		}
+		for ; due >= 1; due-- {
+			*seq++
+			var msg bytes.Buffer
+			if err := t.Execute(&msg, nil); err != nil {
+				kingpin.Fatalf("cannot render the template: %s", err)
+			}
+			size := cloudwatch.MessageSize(msg.String())
+			if cloudwatch.BatchFull(len(batch), batchBytes, size) {
+				flush()
+			}
+			batch = append(batch, &cloudwatchlogs.InputLogEvent{
+				Message:   aws.String(msg.String()),
+				Timestamp: aws.Int64(time.Now().UnixNano() / int64(time.Millisecond)),
+			})
+			batchBytes += size
+		}
+		flush()
+	}
+}
+
+func loadgen() {
+	rate, err := parseRate(*loadgenRate)
+	kingpin.FatalIfError(err, "")
+	if *loadgenStreams < 1 {
+		kingpin.Fatalf("at least one stream is required")
+	}
+
+	target := cloudwatch.Target{}
+	g := newGuard("loadgen", target)
+	if !g.allow("Write synthetic events to", *loadgenGroup, *loadgenGroup, true) {
+		return
+	}
+
+	deadline := time.Now().Add(*loadgenDuration)
+	stats := &loadgenStats{}
+	var writers []*cloudwatch.StreamWriter
+	var wg sync.WaitGroup
+	for i := 1; i <= *loadgenStreams; i++ {
+		stream := fmt.Sprintf("loadgen-%02d", i)
+		w, err := cloudwatch.NewStreamWriter(target, *loadgenGroup, stream)
+		kingpin.FatalIfError(err, "cannot create stream %s", stream)
+		seq := new(int64)
+		t, err := parseLoadgenTemplate(*loadgenTemplate, stream, seq)
+		kingpin.FatalIfError(err, "invalid template %s", *loadgenTemplate)
+		writers = append(writers, w)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loadgenStream(w, t, seq, rate/float64(*loadgenStreams), deadline, stats)
+		}()
+	}
+
+	start := time.Now()
+	fmt.Printf("Writing %.1f events/s to %d streams of %s for %s\n", rate, len(writers), *loadgenGroup, *loadgenDuration)
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+
+	var putErr error
+	if stats.errors > 0 {
+		putErr = fmt.Errorf("%d failed batches", stats.errors)
+	}
+	g.record("put", *loadgenGroup, putErr)
+
+	var throttles int64
+	for _, w := range writers {
+		throttles += w.Throttles()
+	}
+	fmt.Printf("%s %d events, %s in %.0fs: %.1f events/s, %s/s\n", color.GreenString("Sent"),
+		stats.events, formatBytes(stats.bytes), elapsed, float64(stats.events)/elapsed, formatBytes(int64(float64(stats.bytes)/elapsed)))
+	if throttles > 0 || stats.errors > 0 {
+		fmt.Printf("%s %d throttled requests, %d failed batches\n", color.YellowString("Warning:"), throttles, stats.errors)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate     string
+		expected float64
+	}{
+		{"500", 500},
+		{"500/s", 500},
+		{"120/m", 2},
+		{"7200/h", 2},
+		{"0.5/s", 0.5},
+	}
+	for _, tt := range tests {
+		r, err := parseRate(tt.rate)
+		assert.NoError(t, err, tt.rate)
+		assert.Equal(t, tt.expected, r, tt.rate)
+	}
+
+	for _, rate := range []string{"", "fast", "0/s", "-1/s", "10/d", "10/"} {
+		_, err := parseRate(rate)
+		assert.Error(t, err, rate)
+	}
+}
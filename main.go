@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/fatih/color"
 	"github.com/lucagrulla/cw/cloudwatch"
 	"github.com/lucagrulla/cw/timeutil"
@@ -19,6 +20,8 @@ import (
 var (
 	lsCommand      = kingpin.Command("ls", "Show an entity")
 	lsGroups       = lsCommand.Command("groups", "Show all groups.")
+	lsGroupsTree   = lsGroups.Flag("tree", "Show the groups as a tree split on '/', with aggregated stored bytes, group counts and retentions.").Bool()
+	lsGroupsDepth  = lsGroups.Flag("depth", "Collapse the tree levels deeper than depth. 0 shows all the levels.").Default("0").Int()
 	lsStreams      = lsCommand.Command("streams", "Show all streams in a given log group.")
 	lsLogGroupName = lsStreams.Arg("group", "the group name").HintAction(groupsCompletion).Required().String()
 
@@ -29,11 +32,18 @@ var (
 	printEventID    = tailCommand.Flag("event Id", "Print the event Id").Short('i').Default("false").Bool()
 	printStreamName = tailCommand.Flag("stream name", "Print the log stream name this event belongs to.").Short('s').Default("false").Bool()
 	grep            = tailCommand.Flag("grep", "Pattern to filter logs by. See http://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/FilterAndPatternSyntax.html for syntax.").Short('g').Default("").String()
-	logGroupName    = tailCommand.Arg("group", "The log group name.").Required().HintAction(groupsCompletion).String()
+	logGroupName    = tailCommand.Arg("group", "The log group name. Not needed with --eks.").HintAction(groupsCompletion).String()
 	logStreamName   = tailCommand.Arg("stream", "The log stream name. Use \\* for tail all the group streams.").Default("*").HintAction(streamsCompletion).String()
 	startTime       = tailCommand.Arg("start", "The tailing start time in UTC. If a timestamp is passed(format: hh[:mm]) it's expanded to today at the given time. Full format: 2017-02-27[T09:00[:00]].").
 			Default(time.Now().UTC().Add(-30 * time.Second).Format(timeutil.TimeFormat)).String()
 	endTime = tailCommand.Arg("end", "The tailing end time in UTC. If a timestamp is passed(format: hh[:mm]) it's expanded to today at the given time. Full format: 2017-02-27[T09:00[:00]].").String()
+
+	since           = tailCommand.Flag("since", "The tailing start time, as a duration ago(e.g. 2h) or in the start format. Overrides start.").String()
+	until           = tailCommand.Flag("until", "The tailing end time, as a duration ago(e.g. 1h) or in the end format. Overrides end.").String()
+	s3URL           = tailCommand.Flag("s3", "Read the events Firehose delivered to S3 from a subscription filter, as s3://bucket/prefix. The group can be a pattern, e.g. '/ecs/*'.").String()
+	kinesisStream   = tailCommand.Flag("kinesis", "Read the events from the Kinesis stream a subscription filter of the group delivers to, instead of polling the group.").String()
+	withAlarms      = tailCommand.Flag("with-alarms", "Interleave the state changes of the alarms whose name starts with PREFIX, '*' for all the alarms. Not supported with --follow.").PlaceHolder("PREFIX").String()
+	resetCheckpoint = tailCommand.Flag("reset-checkpoint", "Ignore the Kinesis shards checkpoints and read from the start time.").Bool()
 )
 
 func groupsCompletion() []string {
@@ -81,6 +91,55 @@ func timestampToUTC(timeStamp *string) time.Time {
 	return t
 }
 
+func formatEvent(event *cloudwatchlogs.FilteredLogEvent) string {
+	e := newTailEvent(event)
+	if s, ok := e.render(); ok {
+		return s
+	}
+	msg := e.Message
+	if e.Audit != nil {
+		msg = e.Audit.String()
+	}
+	eventTimestamp := *event.Timestamp / 1000
+	if *printEventID {
+		msg = fmt.Sprintf("%s - %s", color.YellowString(e.EventID), msg)
+	}
+	if *printStreamName {
+		msg = fmt.Sprintf("%s - %s", color.BlueString(e.streamLabel()), msg)
+	}
+	if *printTimestamp {
+		msg = fmt.Sprintf("%s - %s", color.GreenString(timeutil.FormatTimestamp(eventTimestamp)), msg)
+	}
+	return msg
+}
+
+//relativeToUTC parses a duration ago(e.g. 2h) or a timestamp
+func relativeToUTC(timeStamp *string) time.Time {
+	if d, err := time.ParseDuration(*timeStamp); err == nil {
+		return time.Now().UTC().Add(-d)
+	}
+	return timestampToUTC(timeStamp)
+}
+
+//filterEvents filters the events of the sources not supporting --grep and the end time natively
+func filterEvents(in <-chan *cloudwatchlogs.FilteredLogEvent, endTime time.Time) <-chan *cloudwatchlogs.FilteredLogEvent {
+	end := endTime.UnixNano() / int64(time.Millisecond)
+	ch := make(chan *cloudwatchlogs.FilteredLogEvent)
+	go func() {
+		defer close(ch)
+		for event := range in {
+			if !endTime.IsZero() && !*follow && *event.Timestamp > end {
+				continue
+			}
+			if *grep != "" && !cloudwatch.MatchTerms(*grep, *event.Message) {
+				continue
+			}
+			ch <- event
+		}
+	}()
+	return ch
+}
+
 func fetchLatestVersion() chan string {
 	latestVersionChannel := make(chan string, 1)
 	go func() {
@@ -120,10 +179,19 @@ func main() {
 
 	latestVersionChannel := fetchLatestVersion()
 
-	versionCheckOnSigterm(version, latestVersionChannel)
+	//these commands stop gracefully on their own, flushing or deleting what they created
+	if command != "otlp-receiver" && command != "events tail" {
+		versionCheckOnSigterm(version, latestVersionChannel)
+	}
 
 	switch command {
 	case "ls groups":
+		if *lsGroupsTree {
+			groups, err := cloudwatch.DescribeGroups(cloudwatch.Target{}, "")
+			kingpin.FatalIfError(err, "cannot describe log groups")
+			printGroupsTree(groups, *lsGroupsDepth)
+			break
+		}
 		for msg := range cloudwatch.LsGroups() {
 			fmt.Println(*msg)
 		}
@@ -132,26 +200,91 @@ func main() {
 			fmt.Println(*msg)
 		}
 	case "tail":
+		initTailFormat()
 		st := timestampToUTC(startTime)
+		if *since != "" {
+			st = relativeToUTC(since)
+		}
 		var et time.Time
 		if *endTime != "" {
 			et = timestampToUTC(endTime)
 		}
+		if *until != "" {
+			et = relativeToUTC(until)
+		}
 
-		for event := range cloudwatch.Tail(logGroupName, logStreamName, follow, &st, &et, grep) {
-			msg := *event.Message
-			eventTimestamp := *event.Timestamp / 1000
-			if *printEventID {
-				msg = fmt.Sprintf("%s - %s", color.YellowString(*event.EventId), msg)
-			}
-			if *printStreamName {
-				msg = fmt.Sprintf("%s - %s", color.BlueString(*event.LogStreamName), msg)
-			}
-			if *printTimestamp {
-				msg = fmt.Sprintf("%s - %s", color.GreenString(timeutil.FormatTimestamp(eventTimestamp)), msg)
-			}
-			fmt.Println(msg)
+		var components map[string]bool
+		if *eksCluster != "" {
+			components = eksTail()
+		} else if *logGroupName == "" {
+			kingpin.Fatalf("required argument 'group' not provided")
+		}
+
+		var events <-chan *cloudwatchlogs.FilteredLogEvent
+		switch {
+		case *s3URL != "":
+			events = tailS3(st, et)
+		case *kinesisStream != "":
+			events = tailKinesis(st, et)
+		default:
+			events = cloudwatch.Tail(logGroupName, logStreamName, follow, &st, &et, grep)
+		}
+		if components != nil {
+			events = filterComponents(events, components)
+		}
+		if *withAlarms != "" {
+			tailWithAlarms(events, st, et)
+			break
+		}
+		for event := range events {
+			fmt.Println(formatEvent(event))
 		}
+	case "diff":
+		diff()
+	case "usage":
+		usage()
+	case "cost-drivers":
+		costDrivers()
+	case "archive":
+		archive()
+	case "loadgen":
+		loadgen()
+	case "lint":
+		lint()
+	case "iam-policy":
+		iamPolicy()
+	case "doctor":
+		doctor()
+	case "undo":
+		undo()
+	case "athena ddl":
+		athenaDDLStatement()
+	case "athena query":
+		athenaRunQuery()
+	case "pipeline create":
+		pipelineCreateCommand()
+	case "pipeline status":
+		pipelineStatusCommand()
+	case "pipeline rm":
+		pipelineRmCommand()
+	case "otlp-receiver":
+		otlpReceiver()
+	case "events tail":
+		eventsTailCommand()
+	case "metrics ls":
+		metricsList()
+	case "metrics chart":
+		metricsDrawChart()
+	case "alarm create":
+		alarmCreateCommand()
+	case "alarm ls":
+		alarmList()
+	case "org find":
+		orgFindCommand()
+	case "search":
+		search()
+	case "sfn":
+		sfnTimelines()
 	}
 	newVersionMsg(version, latestVersionChannel)
 }
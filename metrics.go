@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	metricsCommand = kingpin.Command("metrics", "Show the metrics published by the metric filters.")
+	metricsLs      = metricsCommand.Command("ls", "List the metrics of the metric filters of a group.").Default()
+	metricsGroup   = metricsLs.Arg("group", "The log group name.").Required().HintAction(groupsCompletion).String()
+	metricsOutput  = metricsLs.Flag("output", "Output format.").Short('o').Default("table").Enum("table", "json")
+	metricsChart   = metricsCommand.Command("chart", "Draw a terminal chart of a metric.")
+	metricsName    = metricsChart.Arg("metric", "The metric as NAMESPACE/METRIC.").Required().String()
+	metricsSince   = metricsChart.Flag("since", "How far back the chart starts.").Default("6h").Duration()
+	metricsStat    = metricsChart.Flag("stat", "The statistic: Sum, Average, Minimum, Maximum, SampleCount or a percentile like p99.").Default("Sum").String()
+	metricsPeriod  = metricsChart.Flag("period", "The period of the datapoints, a multiple of 1m.").Default("5m").Duration()
+	metricsHeight  = metricsChart.Flag("height", "The chart height in lines.").Default("12").Int()
+	metricsWidth   = metricsChart.Flag("width", "The chart width in columns, the terminal width($COLUMNS) by default.").Int()
+)
+
+//chartBlocks draw the fraction of a line a value fills
+var chartBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+//combineDatapoints merges the values of the periods drawn in the same column consistently with the statistic
+func combineDatapoints(stat string, values []float64) float64 {
+	v := values[0]
+	for _, x := range values[1:] {
+		switch stat {
+		case "Sum", "SampleCount":
+			v += x
+		case "Minimum":
+			v = math.Min(v, x)
+		default:
+			//averages and percentiles can't be merged exactly, the worst is shown
+			v = math.Max(v, x)
+		}
+	}
+	return v
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+//drawChart draws the datapoints as columns of blocks, one or more periods per column
+//The periods without data are left blank
+func drawChart(points []cloudwatch.Datapoint, stat string, start time.Time, end time.Time, period time.Duration, width int, height int) []string {
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	periods := int(end.Sub(start) / period)
+	perColumn := (periods + width - 1) / width
+	if perColumn < 1 {
+		perColumn = 1
+	}
+	columns := make([][]float64, (periods+perColumn-1)/perColumn)
+	for _, p := range points {
+		i := int(p.Time.Sub(start)/period) / perColumn
+		if i >= 0 && i < len(columns) {
+			columns[i] = append(columns[i], p.Value)
+		}
+	}
+
+	values := make([]float64, len(columns))
+	max, min := math.Inf(-1), 0.0
+	for i, c := range columns {
+		if len(c) == 0 {
+			values[i] = math.NaN()
+			continue
+		}
+		values[i] = combineDatapoints(stat, c)
+		max = math.Max(max, values[i])
+		min = math.Min(min, values[i])
+	}
+	if math.IsInf(max, -1) || max == min {
+		max = min + 1
+	}
+
+	labels := []string{formatValue(max), formatValue((max + min) / 2), formatValue(min)}
+	labelWidth := 0
+	for _, l := range labels {
+		if len(l) > labelWidth {
+			labelWidth = len(l)
+		}
+	}
+	var lines []string
+	for row := height - 1; row >= 0; row-- {
+		label := ""
+		switch row {
+		case height - 1:
+			label = labels[0]
+		case (height - 1) / 2:
+			label = labels[1]
+		case 0:
+			label = labels[2]
+		}
+		var line strings.Builder
+		fmt.Fprintf(&line, "%*s ┤", labelWidth, label)
+		for _, v := range values {
+			if math.IsNaN(v) {
+				line.WriteRune(' ')
+				continue
+			}
+			//how many eighths of this row the value fills
+			fill := int(math.Round((v-min)/(max-min)*float64(height*8))) - row*8
+			switch {
+			case fill <= 0:
+				line.WriteRune(' ')
+			case fill >= 8:
+				line.WriteRune(chartBlocks[8])
+			default:
+				line.WriteRune(chartBlocks[fill])
+			}
+		}
+		lines = append(lines, color.GreenString(line.String()))
+	}
+
+	axis := strings.Repeat(" ", labelWidth+1) + "└" + strings.Repeat("─", len(values))
+	from, to := start.Local().Format("Jan 02 15:04"), end.Local().Format("Jan 02 15:04")
+	ticks := strings.Repeat(" ", labelWidth+2) + from
+	if gap := len(values) - len(from) - len(to); gap > 0 {
+		ticks += strings.Repeat(" ", gap) + to
+	}
+	return append(lines, axis, ticks)
+}
+
+func metricsList() {
+	filters, err := cloudwatch.MetricFilters(cloudwatch.Target{}, *metricsGroup)
+	kingpin.FatalIfError(err, "cannot describe the metric filters of %s", *metricsGroup)
+	if *metricsOutput == "json" {
+		printJSON(filters)
+		return
+	}
+	var rows [][]string
+	for _, f := range filters {
+		rows = append(rows, []string{f.Filter, f.Namespace + "/" + f.Metric, f.Value, f.Pattern})
+	}
+	printTable([]string{"FILTER", "METRIC", "VALUE", "PATTERN"}, rows)
+}
+
+func metricsDrawChart() {
+	namespace, metric, err := cloudwatch.ParseMetric(*metricsName)
+	kingpin.FatalIfError(err, "")
+	if !cloudwatch.IsStatistic(*metricsStat) {
+		kingpin.Fatalf("invalid statistic %q", *metricsStat)
+	}
+	if *metricsPeriod < time.Minute || *metricsPeriod%time.Minute != 0 {
+		kingpin.Fatalf("the period must be a multiple of 1m")
+	}
+	width := *metricsWidth
+	if width == 0 {
+		width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+		if width == 0 {
+			width = 80
+		}
+	}
+	//the y axis labels take up to 12 columns
+	if width <= 12 {
+		kingpin.Fatalf("the chart width must be more than 12 columns, got %d (--width or $COLUMNS)", width)
+	}
+	if *metricsHeight < 1 {
+		kingpin.Fatalf("the chart height must be at least 1 line")
+	}
+
+	end := time.Now().UTC().Truncate(*metricsPeriod)
+	start := end.Add(-*metricsSince).Truncate(*metricsPeriod)
+	points, err := cloudwatch.MetricStatistics(cloudwatch.Target{}, namespace, metric, *metricsStat, start, end, *metricsPeriod)
+	kingpin.FatalIfError(err, "cannot get the statistics of %s", *metricsName)
+
+	unit := ""
+	var total float64
+	for _, p := range points {
+		total += p.Value
+		if p.Unit != "None" {
+			unit = p.Unit
+		}
+	}
+	if unit != "" {
+		unit = fmt.Sprintf(" (%s)", unit)
+	}
+	fmt.Printf("%s %s per %s%s\n", color.New(color.Bold).Sprint(*metricsName), *metricsStat, *metricsPeriod, unit)
+	if len(points) == 0 {
+		fmt.Println("No datapoints.")
+		return
+	}
+	for _, l := range drawChart(points, *metricsStat, start, end, *metricsPeriod, width-12, *metricsHeight) {
+		fmt.Println(l)
+	}
+	if *metricsStat == "Sum" || *metricsStat == "SampleCount" {
+		fmt.Printf("Total %s over %d datapoints\n", formatValue(total), len(points))
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrawChart(t *testing.T) {
+	start := time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+	points := []cloudwatch.Datapoint{
+		{Time: start, Value: 1},
+		{Time: start.Add(time.Minute), Value: 4},
+		{Time: start.Add(3 * time.Minute), Value: 2},
+	}
+	lines := drawChart(points, "Sum", start, start.Add(4*time.Minute), time.Minute, 10, 4)
+	assert.Len(t, lines, 6)
+	plain := func(s string) string { return ansiEscape.ReplaceAllString(s, "") }
+	//one column per period, the third one without data
+	assert.Equal(t, "4 ┤ █  ", plain(lines[0]))
+	assert.Equal(t, "0 ┤██ █", plain(lines[3]))
+	assert.True(t, strings.HasPrefix(lines[4], "  └────"))
+}
+
+func TestDrawChartMergesPeriods(t *testing.T) {
+	start := time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+	var points []cloudwatch.Datapoint
+	for i := 0; i < 60; i++ {
+		points = append(points, cloudwatch.Datapoint{Time: start.Add(time.Duration(i) * time.Minute), Value: float64(i)})
+	}
+	lines := drawChart(points, "Maximum", start, start.Add(time.Hour), time.Minute, 20, 3)
+	axis := lines[len(lines)-2]
+	assert.Equal(t, 20, utf8.RuneCountInString(strings.TrimLeft(axis, " └")))
+}
+
+func TestDrawChartTinySizes(t *testing.T) {
+	color.NoColor = true
+	start := time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+	points := []cloudwatch.Datapoint{{Time: start, Value: 1}}
+	assert.NotPanics(t, func() { drawChart(points, "Sum", start, start.Add(time.Hour), time.Minute, 0, 0) })
+	assert.NotPanics(t, func() { drawChart(points, "Sum", start, start.Add(time.Hour), time.Minute, -5, -1) })
+}
+
+func TestCombineDatapoints(t *testing.T) {
+	assert.Equal(t, 6.0, combineDatapoints("Sum", []float64{1, 2, 3}))
+	assert.Equal(t, 1.0, combineDatapoints("Minimum", []float64{3, 1, 2}))
+	assert.Equal(t, 3.0, combineDatapoints("p99", []float64{3, 1, 2}))
+}
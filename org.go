@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	orgCommand     = kingpin.Command("org", "Work across the accounts of the organization.")
+	orgFind        = orgCommand.Command("find", "Find the log groups matching a pattern in all the accounts of the organization.")
+	orgPattern     = orgFind.Arg("pattern", "The log group name pattern, e.g. '/ecs/*api*'.").Required().String()
+	orgRole        = orgFind.Flag("role", "The role assumed in every account.").Default("OrganizationAccountAccessRole").String()
+	orgRegions     = orgFind.Flag("regions", "Comma separated regions, the configured region by default.").String()
+	orgAccounts    = orgFind.Flag("accounts", "Comma separated account IDs, all the active accounts by default.").String()
+	orgConcurrency = orgFind.Flag("concurrency", "How many accounts and regions are searched at once.").Default("8").Int()
+	orgOutput      = orgFind.Flag("output", "Output format.").Short('o').Default("table").Enum("table", "csv", "json")
+)
+
+type orgGroup struct {
+	Account     string `json:"account"`
+	AccountName string `json:"accountName"`
+	Region      string `json:"region"`
+	*cloudwatch.LogGroup
+}
+
+func orgFindCommand() {
+	if *orgConcurrency < 1 {
+		kingpin.Fatalf("--concurrency must be at least 1")
+	}
+	base := cloudwatch.Target{}
+	identity, err := cloudwatch.CallerIdentity(base)
+	kingpin.FatalIfError(err, "cannot get the caller identity")
+	accounts, err := cloudwatch.ListAccounts(base)
+	kingpin.FatalIfError(err, "cannot list the accounts of the organization")
+	if selected := splitList(*orgAccounts); len(selected) > 0 {
+		var filtered []cloudwatch.Account
+		for _, a := range accounts {
+			if contains(selected, a.ID) {
+				filtered = append(filtered, a)
+			}
+		}
+		accounts = filtered
+	}
+	regions := splitList(*orgRegions)
+	if len(regions) == 0 {
+		region, err := cloudwatch.ResolveRegion(base)
+		kingpin.FatalIfError(err, "cannot resolve the configured region")
+		if region == "" {
+			kingpin.Fatalf("no region configured, set --regions")
+		}
+		regions = []string{region}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var found []orgGroup
+	var failures int
+	sem := make(chan struct{}, *orgConcurrency)
+	for _, a := range accounts {
+		for _, r := range regions {
+			wg.Add(1)
+			go func(a cloudwatch.Account, region string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				target := cloudwatch.AccountTarget(base, identity.Account, a.ID, *orgRole, region)
+				groups, err := cloudwatch.DescribeGroups(target, *orgPattern)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					failures++
+					fmt.Fprintf(os.Stderr, "%s %s(%s) %s: %s\n", color.YellowString("Warning:"), a.ID, a.Name, region, err)
+					return
+				}
+				for _, g := range groups {
+					found = append(found, orgGroup{a.ID, a.Name, region, g})
+				}
+			}(a, r)
+		}
+	}
+	wg.Wait()
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Name != found[j].Name {
+			return found[i].Name < found[j].Name
+		}
+		if found[i].Account != found[j].Account {
+			return found[i].Account < found[j].Account
+		}
+		return found[i].Region < found[j].Region
+	})
+
+	switch *orgOutput {
+	case "json":
+		printJSON(found)
+	default:
+		header := []string{"ACCOUNT", "NAME", "REGION", "GROUP", "SIZE", "RETENTION"}
+		var rows [][]string
+		for _, g := range found {
+			size := formatBytes(g.StoredBytes)
+			if *orgOutput == "csv" {
+				size = strconv.FormatInt(g.StoredBytes, 10)
+			}
+			rows = append(rows, []string{g.Account, g.AccountName, g.Region, g.Name, size, formatRetention(g.RetentionInDays)})
+		}
+		if *orgOutput == "csv" {
+			printCSV(header, rows)
+		} else {
+			printTable(header, rows)
+		}
+	}
+	if failures > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d account regions could not be searched\n", failures, len(accounts)*len(regions))
+	}
+}
@@ -0,0 +1,195 @@
+//Package otlp decodes the OpenTelemetry logs exported over OTLP/HTTP, as protobuf or JSON.
+//Only the ExportLogsServiceRequest message is supported, without any dependency on the protobuf runtime.
+package otlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strconv"
+	"time"
+)
+
+//Content types of the OTLP/HTTP requests
+const (
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeJSON     = "application/json"
+)
+
+//Record is a log record along with the attributes of the resource that emitted it
+type Record struct {
+	Resource       map[string]interface{} `json:"resource,omitempty"`
+	Scope          string                 `json:"scope,omitempty"`
+	Time           time.Time              `json:"-"`
+	SeverityNumber int64                  `json:"severityNumber,omitempty"`
+	SeverityText   string                 `json:"severity,omitempty"`
+	Body           interface{}            `json:"body,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+	TraceID        string                 `json:"traceId,omitempty"`
+	SpanID         string                 `json:"spanId,omitempty"`
+}
+
+//Decode decodes the body of an export request of the given content type
+//Attribute and body values are decoded to string, bool, int64, float64, []byte, []interface{} and map[string]interface{}
+func Decode(contentType string, body []byte) ([]*Record, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type %q", contentType)
+	}
+	switch mediaType {
+	case ContentTypeProtobuf:
+		return decodeProto(body)
+	case ContentTypeJSON:
+		return decodeJSON(body)
+	}
+	return nil, fmt.Errorf("unsupported content type %q", mediaType)
+}
+
+//setTime sets the time of the record, the observed time when the event time is unknown
+func (r *Record) setTime(timeUnixNano uint64, observedUnixNano uint64) {
+	switch {
+	case timeUnixNano > 0:
+		r.Time = time.Unix(0, int64(timeUnixNano))
+	case observedUnixNano > 0:
+		r.Time = time.Unix(0, int64(observedUnixNano))
+	default:
+		r.Time = time.Now()
+	}
+}
+
+//number is an OTLP/JSON 64 bit integer, encoded as a string or as a number
+type number uint64
+
+func (n *number) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if uq, err := strconv.Unquote(s); err == nil {
+		s = uq
+	}
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s", b)
+	}
+	*n = number(v)
+	return nil
+}
+
+type jsonRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []jsonKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []jsonScopeLogs `json:"scopeLogs"`
+		//before OTLP 0.15
+		InstrumentationLibraryLogs []jsonScopeLogs `json:"instrumentationLibraryLogs"`
+	} `json:"resourceLogs"`
+}
+
+type jsonScopeLogs struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	InstrumentationLibrary struct {
+		Name string `json:"name"`
+	} `json:"instrumentationLibrary"`
+	LogRecords []struct {
+		TimeUnixNano         number         `json:"timeUnixNano"`
+		ObservedTimeUnixNano number         `json:"observedTimeUnixNano"`
+		SeverityNumber       number         `json:"severityNumber"`
+		SeverityText         string         `json:"severityText"`
+		Body                 *jsonAnyValue  `json:"body"`
+		Attributes           []jsonKeyValue `json:"attributes"`
+		TraceID              string         `json:"traceId"`
+		SpanID               string         `json:"spanId"`
+	} `json:"logRecords"`
+}
+
+type jsonKeyValue struct {
+	Key   string        `json:"key"`
+	Value *jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string  `json:"stringValue"`
+	BoolValue   *bool    `json:"boolValue"`
+	IntValue    *number  `json:"intValue"`
+	DoubleValue *float64 `json:"doubleValue"`
+	ArrayValue  *struct {
+		Values []*jsonAnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []jsonKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+	BytesValue []byte `json:"bytesValue"`
+}
+
+func (v *jsonAnyValue) value() interface{} {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.ArrayValue != nil:
+		values := make([]interface{}, len(v.ArrayValue.Values))
+		for i, item := range v.ArrayValue.Values {
+			values[i] = item.value()
+		}
+		return values
+	case v.KvlistValue != nil:
+		return jsonAttributes(v.KvlistValue.Values)
+	case v.BytesValue != nil:
+		return v.BytesValue
+	}
+	return nil
+}
+
+func jsonAttributes(kvs []jsonKeyValue) map[string]interface{} {
+	if len(kvs) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(kvs))
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value.value()
+	}
+	return m
+}
+
+func decodeJSON(body []byte) ([]*Record, error) {
+	var req jsonRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	var records []*Record
+	for _, rl := range req.ResourceLogs {
+		resource := jsonAttributes(rl.Resource.Attributes)
+		for _, sl := range append(rl.ScopeLogs, rl.InstrumentationLibraryLogs...) {
+			scope := sl.Scope.Name
+			if scope == "" {
+				scope = sl.InstrumentationLibrary.Name
+			}
+			for _, lr := range sl.LogRecords {
+				r := &Record{Resource: resource, Scope: scope, SeverityNumber: int64(lr.SeverityNumber), SeverityText: lr.SeverityText,
+					Body: lr.Body.value(), Attributes: jsonAttributes(lr.Attributes), TraceID: lr.TraceID, SpanID: lr.SpanID}
+				r.setTime(uint64(lr.TimeUnixNano), uint64(lr.ObservedTimeUnixNano))
+				records = append(records, r)
+			}
+		}
+	}
+	return records, nil
+}
+
+func hexID(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,241 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+//protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+//field is a decoded protobuf field, v holds the numeric value and data the length delimited bytes
+type field struct {
+	num  int
+	typ  int
+	v    uint64
+	data []byte
+}
+
+func varint(b []byte) (uint64, int, error) {
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, 0, errTruncated
+	}
+	return v, n, nil
+}
+
+//fields calls fn for every field of the message, in wire order
+func fields(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		key, n, err := varint(b)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+		f := field{num: int(key >> 3), typ: int(key & 7)}
+		switch f.typ {
+		case wireVarint:
+			if f.v, n, err = varint(b); err != nil {
+				return err
+			}
+		case wireFixed64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			f.v, n = binary.LittleEndian.Uint64(b), 8
+		case wireFixed32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			f.v, n = uint64(binary.LittleEndian.Uint32(b)), 4
+		case wireBytes:
+			l, m, err := varint(b)
+			if err != nil {
+				return err
+			}
+			if uint64(len(b)-m) < l {
+				return errTruncated
+			}
+			f.data, n = b[m:m+int(l)], m+int(l)
+		default:
+			return errors.New("unsupported protobuf wire type")
+		}
+		b = b[n:]
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//decodeProto decodes an ExportLogsServiceRequest
+func decodeProto(b []byte) ([]*Record, error) {
+	var records []*Record
+	err := fields(b, func(f field) error {
+		if f.num != 1 || f.typ != wireBytes {
+			return nil
+		}
+		rs, err := protoResourceLogs(f.data)
+		records = append(records, rs...)
+		return err
+	})
+	return records, err
+}
+
+//protoResourceLogs decodes a ResourceLogs, its ScopeLogs(2) or, before OTLP 0.15, InstrumentationLibraryLogs(1000)
+func protoResourceLogs(b []byte) ([]*Record, error) {
+	var resource map[string]interface{}
+	var scopeLogs [][]byte
+	err := fields(b, func(f field) error {
+		switch {
+		case f.num == 1 && f.typ == wireBytes:
+			return fields(f.data, func(f field) error {
+				if f.num == 1 && f.typ == wireBytes {
+					return protoKeyValue(f.data, &resource)
+				}
+				return nil
+			})
+		case (f.num == 2 || f.num == 1000) && f.typ == wireBytes:
+			scopeLogs = append(scopeLogs, f.data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var records []*Record
+	for _, sl := range scopeLogs {
+		first := len(records)
+		var scope string
+		err := fields(sl, func(f field) error {
+			switch {
+			case f.num == 1 && f.typ == wireBytes:
+				return fields(f.data, func(f field) error {
+					if f.num == 1 && f.typ == wireBytes {
+						scope = string(f.data)
+					}
+					return nil
+				})
+			case f.num == 2 && f.typ == wireBytes:
+				r, err := protoLogRecord(f.data)
+				if err != nil {
+					return err
+				}
+				r.Resource = resource
+				records = append(records, r)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		//the scope can follow the records on the wire
+		for _, r := range records[first:] {
+			r.Scope = scope
+		}
+	}
+	return records, nil
+}
+
+func protoLogRecord(b []byte) (*Record, error) {
+	r := &Record{}
+	var timeUnixNano, observedUnixNano uint64
+	err := fields(b, func(f field) error {
+		var err error
+		//fields of an unexpected wire type are skipped like the unknown ones
+		switch {
+		case f.num == 1 && f.typ == wireFixed64:
+			timeUnixNano = f.v
+		case f.num == 11 && f.typ == wireFixed64:
+			observedUnixNano = f.v
+		case f.num == 2 && f.typ == wireVarint:
+			r.SeverityNumber = int64(f.v)
+		case f.num == 3 && f.typ == wireBytes:
+			r.SeverityText = string(f.data)
+		case f.num == 5 && f.typ == wireBytes:
+			r.Body, err = protoAnyValue(f.data)
+		case f.num == 6 && f.typ == wireBytes:
+			err = protoKeyValue(f.data, &r.Attributes)
+		case f.num == 9 && f.typ == wireBytes:
+			r.TraceID = hexID(f.data)
+		case f.num == 10 && f.typ == wireBytes:
+			r.SpanID = hexID(f.data)
+		}
+		return err
+	})
+	r.setTime(timeUnixNano, observedUnixNano)
+	return r, err
+}
+
+//protoKeyValue decodes a KeyValue into the attributes map, allocating it if needed
+func protoKeyValue(b []byte, attributes *map[string]interface{}) error {
+	var key string
+	var value interface{}
+	err := fields(b, func(f field) error {
+		var err error
+		switch {
+		case f.num == 1 && f.typ == wireBytes:
+			key = string(f.data)
+		case f.num == 2 && f.typ == wireBytes:
+			value, err = protoAnyValue(f.data)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if *attributes == nil {
+		*attributes = make(map[string]interface{})
+	}
+	(*attributes)[key] = value
+	return nil
+}
+
+func protoAnyValue(b []byte) (interface{}, error) {
+	var value interface{}
+	err := fields(b, func(f field) error {
+		var err error
+		switch {
+		case f.num == 1 && f.typ == wireBytes:
+			value = string(f.data)
+		case f.num == 2 && f.typ == wireVarint:
+			value = f.v != 0
+		case f.num == 3 && f.typ == wireVarint:
+			value = int64(f.v)
+		case f.num == 4 && f.typ == wireFixed64:
+			value = math.Float64frombits(f.v)
+		case f.num == 5 && f.typ == wireBytes:
+			values := []interface{}{}
+			err = fields(f.data, func(f field) error {
+				if f.num != 1 || f.typ != wireBytes {
+					return nil
+				}
+				v, err := protoAnyValue(f.data)
+				values = append(values, v)
+				return err
+			})
+			value = values
+		case f.num == 6 && f.typ == wireBytes:
+			kvlist := map[string]interface{}{}
+			err = fields(f.data, func(f field) error {
+				if f.num != 1 || f.typ != wireBytes {
+					return nil
+				}
+				return protoKeyValue(f.data, &kvlist)
+			})
+			value = kvlist
+		case f.num == 7 && f.typ == wireBytes:
+			value = f.data
+		}
+		return err
+	})
+	return value, err
+}
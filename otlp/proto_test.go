@@ -0,0 +1,172 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//a minimal protobuf encoder building the test messages
+
+func pbKey(num int, typ int) []byte {
+	return pbUvarint(uint64(num<<3 | typ))
+}
+
+func pbUvarint(v uint64) []byte {
+	b := make([]byte, binary.MaxVarintLen64)
+	return b[:binary.PutUvarint(b, v)]
+}
+
+func pbVarint(num int, v uint64) []byte {
+	return append(pbKey(num, wireVarint), pbUvarint(v)...)
+}
+
+func pbFixed64(num int, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return append(pbKey(num, wireFixed64), b...)
+}
+
+func pbFixed32(num int, v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return append(pbKey(num, wireFixed32), b...)
+}
+
+func pbBytes(num int, parts ...[]byte) []byte {
+	var data []byte
+	for _, p := range parts {
+		data = append(data, p...)
+	}
+	b := append(pbKey(num, wireBytes), pbUvarint(uint64(len(data)))...)
+	return append(b, data...)
+}
+
+func pbString(num int, s string) []byte {
+	return pbBytes(num, []byte(s))
+}
+
+func pbKeyValue(num int, key string, value []byte) []byte {
+	return pbBytes(num, pbString(1, key), pbBytes(2, value))
+}
+
+var logTime = time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+
+func logRecord(extra ...[]byte) []byte {
+	parts := [][]byte{
+		pbFixed64(1, uint64(logTime.UnixNano())),
+		pbVarint(2, 17),
+		pbString(3, "ERROR"),
+		pbBytes(5, pbString(1, "payment failed")),
+		pbKeyValue(6, "http.status", pbVarint(3, 502)),
+		pbBytes(9, []byte{0x01, 0x02}),
+		pbBytes(10, []byte{0xff}),
+	}
+	return pbBytes(2, append(parts, extra...)...)
+}
+
+func request(records ...[]byte) []byte {
+	resource := pbBytes(1, pbKeyValue(1, "service.name", pbString(1, "payments")))
+	scope := pbBytes(1, pbString(1, "checkout"), pbString(2, "1.0"))
+	scopeLogs := pbBytes(2, append(records, scope)...)
+	return pbBytes(1, resource, scopeLogs)
+}
+
+func TestVarint(t *testing.T) {
+	for _, v := range []uint64{0, 1, 127, 128, 300, 1 << 35, math.MaxUint64} {
+		got, n, err := varint(pbUvarint(v))
+		assert.NoError(t, err)
+		assert.Equal(t, v, got)
+		assert.Equal(t, len(pbUvarint(v)), n)
+	}
+	_, _, err := varint([]byte{0x80, 0x80})
+	assert.Equal(t, errTruncated, err)
+	_, _, err = varint(nil)
+	assert.Equal(t, errTruncated, err)
+}
+
+func TestDecodeProtoNestedMessages(t *testing.T) {
+	records, err := Decode(ContentTypeProtobuf, request(logRecord()))
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	r := records[0]
+	assert.Equal(t, map[string]interface{}{"service.name": "payments"}, r.Resource)
+	//the scope follows the records on the wire
+	assert.Equal(t, "checkout", r.Scope)
+	assert.True(t, logTime.Equal(r.Time))
+	assert.Equal(t, int64(17), r.SeverityNumber)
+	assert.Equal(t, "ERROR", r.SeverityText)
+	assert.Equal(t, "payment failed", r.Body)
+	assert.Equal(t, map[string]interface{}{"http.status": int64(502)}, r.Attributes)
+	assert.Equal(t, "0102", r.TraceID)
+	assert.Equal(t, "ff", r.SpanID)
+}
+
+func TestDecodeProtoAnyValues(t *testing.T) {
+	body := pbBytes(5,
+		pbBytes(6,
+			pbKeyValue(1, "ok", pbVarint(2, 1)),
+			pbKeyValue(1, "ratio", pbFixed64(4, math.Float64bits(0.5))),
+			pbKeyValue(1, "raw", pbBytes(7, []byte("x"))),
+			pbKeyValue(1, "tags", pbBytes(5, pbBytes(1, pbString(1, "a")), pbBytes(1, pbVarint(3, 2)))),
+		))
+	records, err := Decode(ContentTypeProtobuf, request(pbBytes(2, body)))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"ok":    true,
+		"ratio": 0.5,
+		"raw":   []byte("x"),
+		"tags":  []interface{}{"a", int64(2)},
+	}, records[0].Body)
+}
+
+func TestDecodeProtoSkipsUnknownFields(t *testing.T) {
+	unknown := [][]byte{
+		pbVarint(99, 1),
+		pbFixed32(98, 7),
+		pbFixed64(97, 7),
+		pbString(96, "ignored"),
+		//a packed repeated field, length delimited varints
+		pbBytes(95, pbUvarint(1), pbUvarint(300), pbUvarint(1<<40)),
+		//a known field number with an unexpected wire type
+		pbBytes(2, pbUvarint(5), pbUvarint(6)),
+	}
+	records, err := Decode(ContentTypeProtobuf, append(request(logRecord(unknown...)), pbVarint(2, 1)...))
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, int64(17), records[0].SeverityNumber)
+	assert.Equal(t, "payment failed", records[0].Body)
+}
+
+func TestDecodeProtoLegacyInstrumentationLibraryLogs(t *testing.T) {
+	legacy := pbBytes(1, pbBytes(1000, pbBytes(2, pbBytes(5, pbString(1, "old")))))
+	records, err := Decode(ContentTypeProtobuf, legacy)
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "old", records[0].Body)
+}
+
+func TestDecodeProtoTruncated(t *testing.T) {
+	full := request(logRecord())
+	for _, n := range []int{1, 2, len(full) / 2, len(full) - 1} {
+		_, err := Decode(ContentTypeProtobuf, full[:n])
+		assert.Equal(t, errTruncated, err, "truncated at %d", n)
+	}
+	_, err := Decode(ContentTypeProtobuf, pbKey(1, wireFixed64))
+	assert.Equal(t, errTruncated, err)
+	_, err = Decode(ContentTypeProtobuf, append(pbKey(1, wireBytes), pbUvarint(math.MaxUint64)...))
+	assert.Equal(t, errTruncated, err)
+	_, err = Decode(ContentTypeProtobuf, pbKey(1, 3))
+	assert.EqualError(t, err, "unsupported protobuf wire type")
+}
+
+func TestDecodeContentTypes(t *testing.T) {
+	_, err := Decode("text/plain", nil)
+	assert.EqualError(t, err, `unsupported content type "text/plain"`)
+	records, err := Decode(ContentTypeProtobuf+"; charset=binary", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/lucagrulla/cw/otlp"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	otlpCommand       = kingpin.Command("otlp-receiver", "Receive OpenTelemetry logs over OTLP/HTTP and write them to CloudWatch Logs.")
+	otlpAddr          = otlpCommand.Flag("addr", "The address to listen on, logs are received on /v1/logs. The endpoint isn't authenticated, only listen on other interfaces behind a firewall.").Default("localhost:4318").String()
+	otlpGroupFrom     = otlpCommand.Flag("group-from", "The resource attribute the log group is named after.").Default("service.name").String()
+	otlpGroupPrefix   = otlpCommand.Flag("group-prefix", "The prefix of the log group names, e.g. /otel/.").Default("").String()
+	otlpDefaultGroup  = otlpCommand.Flag("default-group", "The log group of the resources without the --group-from attribute.").Default("otlp").String()
+	otlpStreamFrom    = otlpCommand.Flag("stream-from", "The resource attribute the log stream is named after.").Default("service.instance.id").String()
+	otlpDefaultStream = otlpCommand.Flag("default-stream", "The log stream of the resources without the --stream-from attribute.").Default("default").String()
+	otlpMessage       = otlpCommand.Flag("message", "The event message: json(the record with its severity, attributes, trace context and resource) or body.").Default("json").Enum("json", "body")
+	otlpFlush         = otlpCommand.Flag("flush", "How often the buffered events are written.").Default("1s").Duration()
+)
+
+const (
+	otlpMaxRequestBytes = 16 << 20
+	otlpMaxMessageBytes = 262144 - 26
+	otlpStreamBuffer    = cloudwatch.MaxBatchEvents
+	//PutLogEvents rejects the events older than 14 days, more than 2 hours in the future or spanning more than 24 hours in a batch
+	otlpMaxAge       = 14 * 24 * time.Hour
+	otlpMaxFuture    = 2 * time.Hour
+	otlpMaxBatchSpan = 24 * time.Hour
+)
+
+var (
+	invalidGroupChars  = regexp.MustCompile(`[^a-zA-Z0-9_./#-]`)
+	invalidStreamChars = regexp.MustCompile(`[:*]`)
+)
+
+type otlpStats struct {
+	events  int64
+	dropped int64
+	errors  int64
+}
+
+//otlpForwarder batches the events of every log stream and writes them with one goroutine per stream
+type otlpForwarder struct {
+	target  cloudwatch.Target
+	g       *guard
+	mu      sync.Mutex
+	streams map[string]chan *cloudwatchlogs.InputLogEvent
+	//closing is set by close, the events added after are dropped
+	closing bool
+	//sending tracks the adds in flight, close waits for them before closing the channels
+	sending sync.WaitGroup
+	wg      sync.WaitGroup
+	stats   otlpStats
+}
+
+func (f *otlpForwarder) add(group string, stream string, event *cloudwatchlogs.InputLogEvent) {
+	key := group + ":" + stream
+	f.mu.Lock()
+	if f.closing {
+		f.mu.Unlock()
+		atomic.AddInt64(&f.stats.dropped, 1)
+		return
+	}
+	ch, ok := f.streams[key]
+	if !ok {
+		ch = make(chan *cloudwatchlogs.InputLogEvent, otlpStreamBuffer)
+		f.streams[key] = ch
+		f.wg.Add(1)
+		go f.forward(group, stream, ch)
+	}
+	f.sending.Add(1)
+	f.mu.Unlock()
+	defer f.sending.Done()
+	ch <- event
+}
+
+func (f *otlpForwarder) forward(group string, stream string, ch <-chan *cloudwatchlogs.InputLogEvent) {
+	defer f.wg.Done()
+	w, err := cloudwatch.NewStreamWriter(f.target, group, stream)
+	f.g.record("put", group, err)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s cannot write to %s %s: %s\n", color.RedString("Error:"), group, stream, err)
+		for range ch {
+			atomic.AddInt64(&f.stats.dropped, 1)
+		}
+		return
+	}
+	fmt.Printf("Writing to %s %s\n", group, stream)
+
+	var batch []*cloudwatchlogs.InputLogEvent
+	var batchBytes int
+	var oldest, newest int64
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.Put(batch); err != nil {
+			atomic.AddInt64(&f.stats.errors, 1)
+			atomic.AddInt64(&f.stats.dropped, int64(len(batch)))
+			fmt.Fprintf(os.Stderr, "%s cannot write to %s %s: %s\n", color.RedString("Error:"), group, stream, err)
+		} else {
+			atomic.AddInt64(&f.stats.events, int64(len(batch)))
+		}
+		batch, batchBytes = nil, 0
+	}
+	ticker := time.NewTicker(*otlpFlush)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				flush()
+				return
+			}
+			size := cloudwatch.MessageSize(*event.Message)
+			ts := *event.Timestamp
+			if len(batch) > 0 && (ts < oldest && newest-ts > int64(otlpMaxBatchSpan/time.Millisecond) ||
+				ts > newest && ts-oldest > int64(otlpMaxBatchSpan/time.Millisecond)) {
+				flush()
+			}
+			if cloudwatch.BatchFull(len(batch), batchBytes, size) {
+				flush()
+			}
+			if len(batch) == 0 || ts < oldest {
+				oldest = ts
+			}
+			if len(batch) == 0 || ts > newest {
+				newest = ts
+			}
+			batch = append(batch, event)
+			batchBytes += size
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+//close writes the buffered events and waits for the writers to finish
+//The adds in flight, e.g. of the requests still running after the server shutdown timeout, complete first
+//as the writers keep draining the channels
+func (f *otlpForwarder) close() {
+	f.mu.Lock()
+	f.closing = true
+	f.mu.Unlock()
+	f.sending.Wait()
+
+	f.mu.Lock()
+	for _, ch := range f.streams {
+		close(ch)
+	}
+	f.streams = make(map[string]chan *cloudwatchlogs.InputLogEvent)
+	f.mu.Unlock()
+	f.wg.Wait()
+}
+
+//otlpName returns the value of the resource attribute, or the fallback when it's missing or empty
+func otlpName(resource map[string]interface{}, attribute string, fallback string) string {
+	if v, ok := resource[attribute]; ok && v != nil {
+		if s := fmt.Sprint(v); s != "" {
+			return s
+		}
+	}
+	return fallback
+}
+
+func otlpEventMessage(r *otlp.Record) string {
+	var message string
+	if *otlpMessage == "body" {
+		if s, ok := r.Body.(string); ok {
+			message = s
+		} else {
+			b, _ := json.Marshal(r.Body)
+			message = string(b)
+		}
+	} else {
+		b, _ := json.Marshal(r)
+		message = string(b)
+	}
+	return truncateUTF8(message, otlpMaxMessageBytes)
+}
+
+//truncateUTF8 cuts s to at most n bytes of valid UTF-8, PutLogEvents rejects the invalid sequences
+func truncateUTF8(s string, n int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func (f *otlpForwarder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body io.Reader = http.MaxBytesReader(w, req.Body, otlpMaxRequestBytes)
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = io.LimitReader(gz, otlpMaxRequestBytes)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	contentType := req.Header.Get("Content-Type")
+	records, err := otlp.Decode(contentType, b)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	for _, r := range records {
+		if r.Time.Before(now.Add(-otlpMaxAge)) || r.Time.After(now.Add(otlpMaxFuture)) {
+			atomic.AddInt64(&f.stats.dropped, 1)
+			continue
+		}
+		group := *otlpGroupPrefix + otlpName(r.Resource, *otlpGroupFrom, *otlpDefaultGroup)
+		group = invalidGroupChars.ReplaceAllString(group, "_")
+		stream := invalidStreamChars.ReplaceAllString(otlpName(r.Resource, *otlpStreamFrom, *otlpDefaultStream), "_")
+		f.add(group, stream, &cloudwatchlogs.InputLogEvent{
+			Message:   aws.String(otlpEventMessage(r)),
+			Timestamp: aws.Int64(r.Time.UnixNano() / int64(time.Millisecond)),
+		})
+	}
+
+	//an empty ExportLogsServiceResponse
+	if strings.HasPrefix(contentType, otlp.ContentTypeJSON) {
+		w.Header().Set("Content-Type", otlp.ContentTypeJSON)
+		w.Write([]byte("{}"))
+		return
+	}
+	w.Header().Set("Content-Type", otlp.ContentTypeProtobuf)
+}
+
+func otlpReceiver() {
+	target := cloudwatch.Target{}
+	g := newGuard("otlp-receiver", target)
+	if !g.allow("Write OpenTelemetry logs to", "the log groups named after "+*otlpGroupFrom, g.profile, true) {
+		return
+	}
+	f := &otlpForwarder{target: target, g: g, streams: make(map[string]chan *cloudwatchlogs.InputLogEvent)}
+	mux := http.NewServeMux()
+	mux.Handle("/v1/logs", f)
+	srv := &http.Server{Addr: *otlpAddr, Handler: mux}
+
+	done := make(chan struct{})
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		<-c
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		close(done)
+	}()
+
+	fmt.Printf("Receiving OTLP logs on http://%s/v1/logs\n", *otlpAddr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		kingpin.FatalIfError(err, "")
+	}
+	<-done
+	f.close()
+	fmt.Printf("%s %d events written, %d dropped, %d failed batches\n", color.GreenString("Stopped."), f.stats.events, f.stats.dropped, f.stats.errors)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateUTF8(t *testing.T) {
+	assert.Equal(t, "héllo", truncateUTF8("héllo", 10))
+	assert.Equal(t, "h", truncateUTF8("hé", 2))
+	assert.Equal(t, "hé", truncateUTF8("héllo", 3))
+	assert.Equal(t, "a�b", truncateUTF8("a\xffb", 10))
+	s := truncateUTF8(strings.Repeat("€", 100), 250)
+	assert.True(t, utf8.ValidString(s))
+	assert.Len(t, s, 249)
+}
+
+func TestOtlpForwarderCloseWaitsForAdds(t *testing.T) {
+	//the stream channel is unbuffered, adds are still blocked on it when close starts
+	ch := make(chan *cloudwatchlogs.InputLogEvent)
+	f := &otlpForwarder{streams: map[string]chan *cloudwatchlogs.InputLogEvent{"g:s": ch}}
+	received := make(chan int)
+	go func() {
+		n := 0
+		for range ch {
+			n++
+		}
+		received <- n
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.add("g", "s", &cloudwatchlogs.InputLogEvent{Message: aws.String("m"), Timestamp: aws.Int64(0)})
+		}()
+	}
+	f.close()
+	wg.Wait()
+	assert.Equal(t, int64(50), int64(<-received)+f.stats.dropped)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+//ansiEscape matches the color sequences, which take no room on the terminal
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+//visibleWidth returns the number of characters s takes on the terminal
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiEscape.ReplaceAllString(s, ""))
+}
+
+//printTable prints the rows as aligned columns under a highlighted header
+func printTable(header []string, rows [][]string) {
+	writeTable(os.Stdout, header, rows)
+}
+
+//writeTable pads the cells by their visible width, tabwriter would count the color sequences
+func writeTable(w io.Writer, header []string, rows [][]string) {
+	bold := make([]string, len(header))
+	for i, h := range header {
+		bold[i] = color.New(color.Bold).Sprint(h)
+	}
+	lines := append([][]string{bold}, rows...)
+	var widths []int
+	for _, line := range lines {
+		for i, cell := range line {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := visibleWidth(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	for _, line := range lines {
+		var b strings.Builder
+		for i, cell := range line {
+			b.WriteString(cell)
+			if i < len(line)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-visibleWidth(cell)+2))
+			}
+		}
+		fmt.Fprintln(w, b.String())
+	}
+}
+
+func printCSV(header []string, rows [][]string) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write(header)
+	w.WriteAll(rows)
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+//formatBytes formats a size in bytes using binary units, e.g. 1.5 GiB
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
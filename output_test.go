@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTableIgnoresColors(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	var b bytes.Buffer
+	writeTable(&b, []string{"GROUP", "SIZE"}, [][]string{
+		{color.RedString("/ecs/api"), "1.0 KiB"},
+		{"/lambda/ingest-événements", color.GreenString("✔")},
+	})
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	assert.Len(t, lines, 3)
+	for _, l := range lines {
+		plain := ansiEscape.ReplaceAllString(l, "")
+		second := strings.Fields(plain)[1]
+		assert.Equal(t, utf8.RuneCountInString("/lambda/ingest-événements")+2, utf8.RuneCountInString(plain[:strings.Index(plain, second)]), plain)
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	assert.Equal(t, 1, visibleWidth("\x1b[32m✔\x1b[0m"))
+	assert.Equal(t, 5, visibleWidth("plain"))
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	pipelineCommand       = kingpin.Command("pipeline", "Deliver a log group to S3 through Firehose.")
+	pipelineCreate        = pipelineCommand.Command("create", "Create the roles, the delivery stream and the subscription filter of the group, and validate the delivery.")
+	pipelineCreateGroup   = pipelineCreate.Arg("group", "The log group name.").Required().HintAction(groupsCompletion).String()
+	pipelineTo            = pipelineCreate.Flag("to", "The S3 destination as s3://bucket/prefix.").Required().String()
+	pipelineFilterPattern = pipelineCreate.Flag("filter-pattern", "The subscription filter pattern, all the events by default. The validation canary has to match it.").Default("").String()
+	pipelineValidate      = pipelineCreate.Flag("validate", "Write a canary event and wait for it to be delivered to S3.").Default("true").Bool()
+	pipelineTimeout       = pipelineCreate.Flag("timeout", "How long to wait for the delivery stream and the canary.").Default("10m").Duration()
+	pipelineCreateYes     = pipelineCreate.Flag("yes", "Don't ask for confirmation, unless the environment is protected.").Bool()
+	pipelineStatus        = pipelineCommand.Command("status", "Show the state of the pipeline of the group.")
+	pipelineStatusGroup   = pipelineStatus.Arg("group", "The log group name.").Required().HintAction(groupsCompletion).String()
+	pipelineRm            = pipelineCommand.Command("rm", "Remove the subscription filter, the delivery stream and the roles of the group pipeline. The delivered objects are kept.")
+	pipelineRmGroup       = pipelineRm.Arg("group", "The log group name.").Required().HintAction(groupsCompletion).String()
+	pipelineRmYes         = pipelineRm.Flag("yes", "Don't ask for confirmation, unless the environment is protected.").Bool()
+)
+
+const (
+	pipelineCanaryStream   = "cw-pipeline-canary"
+	pipelineCanaryInterval = 15 * time.Second
+)
+
+func pipelineStep(step string, err error) {
+	kingpin.FatalIfError(err, "cannot %s", step)
+	fmt.Printf("%s %s\n", color.GreenString("✔"), step)
+}
+
+//validatePipeline writes a canary event to the group and waits for Firehose to deliver it to S3
+func validatePipeline(target cloudwatch.Target, p *cloudwatch.Pipeline, bucket string, prefix string) error {
+	w, err := cloudwatch.NewStreamWriter(target, p.Group, pipelineCanaryStream)
+	if err != nil {
+		return err
+	}
+	start := time.Now().UTC()
+	canary := fmt.Sprintf(`{"cw":"pipeline canary","pipeline":%q,"id":"%x"}`, p.Name, start.UnixNano())
+	event := &cloudwatchlogs.InputLogEvent{Message: aws.String(canary), Timestamp: aws.Int64(start.UnixNano() / int64(time.Millisecond))}
+	if err := w.Put([]*cloudwatchlogs.InputLogEvent{event}); err != nil {
+		return err
+	}
+	deadline := start.Add(*pipelineTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(pipelineCanaryInterval)
+		for e := range cloudwatch.TailS3(target, bucket, prefix, p.Group, pipelineCanaryStream, start.Add(-time.Minute), time.Now().UTC()) {
+			if *e.Message == canary {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("the canary event wasn't delivered in %s", *pipelineTimeout)
+}
+
+func pipelineCreateCommand() {
+	target := cloudwatch.Target{}
+	bucket, prefix, err := cloudwatch.ParseS3URL(*pipelineTo)
+	kingpin.FatalIfError(err, "")
+	region, err := cloudwatch.ResolveRegion(target)
+	kingpin.FatalIfError(err, "")
+	p := cloudwatch.NewPipeline(*pipelineCreateGroup)
+
+	g := newGuard("pipeline", target)
+	if !g.allow("Create pipeline", fmt.Sprintf("%s from %s to %s", p.Name, p.Group, *pipelineTo), p.Group, *pipelineCreateYes) {
+		return
+	}
+
+	trust, policy := cloudwatch.FirehoseRolePolicy(bucket, prefix)
+	firehoseRole, err := cloudwatch.EnsureRole(target, p.FirehoseRole(), trust, policy)
+	g.record("create role", p.FirehoseRole(), err)
+	pipelineStep("create role "+p.FirehoseRole(), err)
+
+	stream, err := cloudwatch.EnsureDeliveryStream(target, p.Name, firehoseRole, bucket, prefix, *pipelineTimeout)
+	g.record("create delivery stream", p.Name, err)
+	pipelineStep("create delivery stream "+p.Name, err)
+	if stream.Bucket != bucket || stream.Prefix != prefix {
+		kingpin.Fatalf("delivery stream %s already delivers to s3://%s", p.Name, path.Join(stream.Bucket, stream.Prefix))
+	}
+
+	trust, policy = cloudwatch.LogsRolePolicy(region, stream.Arn)
+	logsRole, err := cloudwatch.EnsureRole(target, p.LogsRole(), trust, policy)
+	g.record("create role", p.LogsRole(), err)
+	pipelineStep("create role "+p.LogsRole(), err)
+
+	filter := cloudwatch.State{"pattern": *pipelineFilterPattern, "destination": stream.Arn, "roleArn": logsRole}
+	//CloudWatch Logs can't assume a new role for a few seconds
+	err = applyChangeRetrying(g, target, cloudwatch.SubscriptionFilterChange, p.Group, p.Name, filter, isInvalidParameter, time.Now().Add(*pipelineTimeout))
+	pipelineStep("create subscription filter "+p.Name, err)
+
+	if *pipelineValidate {
+		fmt.Printf("Waiting for the canary event to be delivered to %s...\n", *pipelineTo)
+		err := validatePipeline(target, p, bucket, prefix)
+		deleteCanaryStream(g, target, p.Group)
+		pipelineStep("validate the delivery", err)
+	}
+	fmt.Printf("%s %s is delivered to %s\n", color.GreenString("Done."), p.Group, *pipelineTo)
+}
+
+//deleteCanaryStream deletes the stream the validation writes the canary to, only a warning is printed if it fails
+func deleteCanaryStream(g *guard, target cloudwatch.Target, group string) {
+	err := cloudwatch.DeleteStream(target, group, pipelineCanaryStream)
+	g.record("delete stream", pipelineCanaryStream, err)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s cannot delete the %s stream of %s: %s\n", color.YellowString("Warning:"), pipelineCanaryStream, group, err)
+	}
+}
+
+func isInvalidParameter(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == cloudwatchlogs.ErrCodeInvalidParameterException
+}
+
+func pipelineStatusCommand() {
+	target := cloudwatch.Target{}
+	p := cloudwatch.NewPipeline(*pipelineStatusGroup)
+	missing := color.RedString("missing")
+
+	stream, err := cloudwatch.DescribeDeliveryStream(target, p.Name)
+	kingpin.FatalIfError(err, "")
+	streamStatus, destination := missing, ""
+	if stream != nil {
+		streamStatus = stream.Status
+		destination = "s3://" + path.Join(stream.Bucket, stream.Prefix)
+	}
+
+	filter, err := cloudwatch.CurrentState(target, cloudwatch.SubscriptionFilterChange, p.Group, p.Name)
+	kingpin.FatalIfError(err, "")
+	filterStatus, pattern := missing, ""
+	if filter != nil {
+		filterStatus = "ACTIVE"
+		pattern = strconv.Quote(filter["pattern"])
+		if stream == nil || filter["destination"] != stream.Arn {
+			filterStatus = color.RedString("delivers to %s", filter["destination"])
+		}
+	}
+
+	rows := [][]string{
+		{"subscription filter", p.Name, filterStatus, pattern},
+		{"delivery stream", p.Name, streamStatus, destination},
+	}
+	for _, role := range []string{p.FirehoseRole(), p.LogsRole()} {
+		arn, err := cloudwatch.RoleArn(target, role)
+		kingpin.FatalIfError(err, "")
+		status := missing
+		if arn != "" {
+			status = "EXISTS"
+		}
+		rows = append(rows, []string{"role", role, status, arn})
+	}
+
+	if stream != nil {
+		//the latest delivery is searched in the partitions of today and yesterday
+		latest := "none since yesterday"
+		now := time.Now().UTC()
+		for _, day := range []time.Time{now, now.Add(-24 * time.Hour)} {
+			objects, err := cloudwatch.ListObjects(target, stream.Bucket, path.Join(stream.Prefix, day.Format("2006/01/02"))+"/")
+			kingpin.FatalIfError(err, "")
+			if len(objects) > 0 {
+				latest = "s3://" + path.Join(stream.Bucket, objects[len(objects)-1].Key)
+				break
+			}
+		}
+		rows = append(rows, []string{"latest delivery", "", "", latest})
+	}
+	printTable([]string{"COMPONENT", "NAME", "STATUS", "DETAILS"}, rows)
+}
+
+func pipelineRmCommand() {
+	target := cloudwatch.Target{}
+	p := cloudwatch.NewPipeline(*pipelineRmGroup)
+	g := newGuard("pipeline", target)
+	if !g.allow("Remove pipeline", fmt.Sprintf("%s of %s", p.Name, p.Group), p.Group, *pipelineRmYes) {
+		return
+	}
+
+	filter, err := cloudwatch.CurrentState(target, cloudwatch.SubscriptionFilterChange, p.Group, p.Name)
+	kingpin.FatalIfError(err, "")
+	if filter != nil {
+		pipelineStep("delete subscription filter "+p.Name, applyChange(g, target, cloudwatch.SubscriptionFilterChange, p.Group, p.Name, nil))
+	}
+	//left by a validation interrupted before its end
+	deleteCanaryStream(g, target, p.Group)
+	err = cloudwatch.DeleteDeliveryStream(target, p.Name)
+	g.record("delete delivery stream", p.Name, err)
+	pipelineStep("delete delivery stream "+p.Name, err)
+	for _, role := range []string{p.LogsRole(), p.FirehoseRole()} {
+		err = cloudwatch.DeleteRole(target, role)
+		g.record("delete role", role, err)
+		pipelineStep("delete role "+role, err)
+	}
+	fmt.Printf("%s the objects already delivered are kept\n", color.GreenString("Done."))
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+//confirm asks a yes/no question, anything but y/yes is a no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", color.YellowString(question))
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+//confirmTyped asks the user to type the expected value to go ahead
+func confirmTyped(question string, expected string) bool {
+	fmt.Printf("%s\n%s ", color.YellowString(question), color.New(color.Bold).Sprintf("Type %q to confirm:", expected))
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(answer) == expected
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+//tailS3 tails the events Firehose delivered to the --s3 location
+func tailS3(startTime time.Time, endTime time.Time) <-chan *cloudwatchlogs.FilteredLogEvent {
+	if *follow {
+		kingpin.Fatalf("--follow is not supported with --s3")
+	}
+	bucket, prefix, err := cloudwatch.ParseS3URL(*s3URL)
+	kingpin.FatalIfError(err, "")
+	return filterEvents(cloudwatch.TailS3(cloudwatch.Target{}, bucket, prefix, *logGroupName, *logStreamName, startTime, endTime), endTime)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/lucagrulla/cw/timeutil"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	searchCommand       = kingpin.Command("search", "Search a term in the events of many log groups.")
+	searchTerm          = searchCommand.Arg("term", "The term to search, e.g. an IP address or a user ID.").Required().String()
+	searchGroupsPattern = searchCommand.Flag("groups-pattern", "The pattern of the log groups to search.").Default("*").String()
+	searchSince         = searchCommand.Flag("since", "How far back the search starts.").Default("6h").Duration()
+	searchConcurrency   = searchCommand.Flag("concurrency", "How many groups are searched at once.").Default("8").Int()
+	searchRate          = searchCommand.Flag("rate", "The maximum FilterLogEvents requests per second, shared by all the groups.").Default("5").Int()
+	searchRaw           = searchCommand.Flag("raw", "Use the term as a filter pattern instead of an exact phrase.").Bool()
+)
+
+//searchPattern quotes the term so that it matches as an exact phrase, e.g. an IP address
+func searchPattern(term string) string {
+	if *searchRaw {
+		return term
+	}
+	return strconv.Quote(strings.Trim(term, `"`))
+}
+
+type searchResult struct {
+	group string
+	hits  int
+	err   error
+}
+
+func search() {
+	if *searchConcurrency < 1 || *searchRate < 1 {
+		kingpin.Fatalf("--concurrency and --rate must be at least 1")
+	}
+	target := cloudwatch.Target{}
+	groups, err := cloudwatch.DescribeGroups(target, *searchGroupsPattern)
+	kingpin.FatalIfError(err, "cannot describe the log groups")
+	if len(groups) == 0 {
+		kingpin.Fatalf("no log group matches %s", *searchGroupsPattern)
+	}
+
+	end := time.Now().UTC()
+	start := end.Add(-*searchSince)
+	limiter := cloudwatch.NewRateLimiter(*searchRate)
+	pattern := searchPattern(*searchTerm)
+	fmt.Fprintf(os.Stderr, "Searching %s in %d groups since %s\n", pattern, len(groups), start.Format(time.RFC3339))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var results []searchResult
+	sem := make(chan struct{}, *searchConcurrency)
+	for _, g := range groups {
+		wg.Add(1)
+		go func(group string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			r := searchResult{group: group}
+			q := cloudwatch.Query{Group: group, Start: start, End: end, Pattern: pattern, Limiter: limiter}
+			r.err = cloudwatch.Events(target, q, func(event *cloudwatchlogs.FilteredLogEvent) bool {
+				mu.Lock()
+				defer mu.Unlock()
+				r.hits++
+				fmt.Printf("%s %s %s\n", color.BlueString(group), color.GreenString(timeutil.FormatTimestamp(*event.Timestamp/1000)), *event.Message)
+				return true
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, r)
+			status := fmt.Sprintf("%d hits", r.hits)
+			if r.err != nil {
+				status = color.RedString(r.err.Error())
+			}
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s: %s\n", len(results), len(groups), group, status)
+		}(g.Name)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].hits != results[j].hits {
+			return results[i].hits > results[j].hits
+		}
+		return results[i].group < results[j].group
+	})
+	var rows [][]string
+	var total, failed int
+	for _, r := range results {
+		total += r.hits
+		switch {
+		case r.err != nil:
+			failed++
+			rows = append(rows, []string{r.group, strconv.Itoa(r.hits), color.RedString("failed")})
+		case r.hits > 0:
+			rows = append(rows, []string{r.group, strconv.Itoa(r.hits), ""})
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+	if len(rows) > 0 {
+		printTable([]string{"GROUP", "HITS", ""}, rows)
+	}
+	fmt.Printf("%d hits in %d of %d groups", total, len(rows)-failed, len(groups))
+	if failed > 0 {
+		fmt.Printf(", %d groups failed", failed)
+	}
+	fmt.Println()
+}
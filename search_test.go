@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchPattern(t *testing.T) {
+	assert.Equal(t, `"payment failed"`, searchPattern("payment failed"))
+	assert.Equal(t, `"payment failed"`, searchPattern(`"payment failed"`))
+	assert.Equal(t, `"req-42"`, searchPattern("req-42"))
+}
+
+func TestSearchPatternRaw(t *testing.T) {
+	*searchRaw = true
+	defer func() { *searchRaw = false }()
+	assert.Equal(t, `{ $.status = 500 }`, searchPattern(`{ $.status = 500 }`))
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/lucagrulla/cw/timeutil"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	sfnCommand = kingpin.Command("sfn", "Show the executions a Step Functions state machine logged as timelines of states.")
+	sfnRef     = sfnCommand.Arg("execution|state machine", "An execution ARN, or a state machine name or ARN.").Required().String()
+	sfnSince   = sfnCommand.Flag("since", "How far back the executions are searched.").Default("1h").Duration()
+	sfnGroup   = sfnCommand.Flag("group", "The log group of the state machine, when it isn't named /aws/vendedlogs/states/NAME-Logs or under /aws/vendedlogs/states/NAME/.").String()
+)
+
+//sfnEvent is a state machine history event as logged to CloudWatch
+type sfnEvent struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Timestamp string `json:"event_timestamp"`
+	Execution string `json:"execution_arn"`
+	Details   struct {
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+		Error string          `json:"error"`
+		Cause string          `json:"cause"`
+	} `json:"details"`
+	time time.Time
+}
+
+type sfnState struct {
+	name    string
+	entered time.Time
+	exited  time.Time
+	input   string
+	err     string
+}
+
+type sfnExecution struct {
+	arn    string
+	start  time.Time
+	end    time.Time
+	status string
+	input  string
+	err    string
+	states []*sfnState
+}
+
+func (e *sfnExecution) failed() bool {
+	return e.status == "FAILED" || e.status == "TIMED_OUT" || e.status == "ABORTED"
+}
+
+//sfnText renders an input logged either as a JSON string or as a JSON value
+func sfnText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+func sfnError(e *sfnEvent) string {
+	if e.Details.Cause == "" {
+		return e.Details.Error
+	}
+	return fmt.Sprintf("%s: %s", e.Details.Error, e.Details.Cause)
+}
+
+//newSfnExecution builds the timeline of an execution from its events
+func newSfnExecution(arn string, events []*sfnEvent) *sfnExecution {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].time.Equal(events[j].time) {
+			return events[i].time.Before(events[j].time)
+		}
+		a, _ := strconv.Atoi(events[i].ID)
+		b, _ := strconv.Atoi(events[j].ID)
+		return a < b
+	})
+	x := &sfnExecution{arn: arn, status: "RUNNING"}
+	//the states entered and not exited yet, the failures belong to the last one
+	var open []*sfnState
+	for _, e := range events {
+		switch {
+		case e.Type == "ExecutionStarted":
+			x.start, x.input = e.time, sfnText(e.Details.Input)
+		case e.Type == "ExecutionSucceeded":
+			x.end, x.status = e.time, "SUCCEEDED"
+		case e.Type == "ExecutionFailed" || e.Type == "ExecutionTimedOut" || e.Type == "ExecutionAborted":
+			x.end, x.err = e.time, sfnError(e)
+			x.status = map[string]string{"ExecutionFailed": "FAILED", "ExecutionTimedOut": "TIMED_OUT", "ExecutionAborted": "ABORTED"}[e.Type]
+		case strings.HasSuffix(e.Type, "StateEntered"):
+			s := &sfnState{name: e.Details.Name, entered: e.time, input: sfnText(e.Details.Input)}
+			x.states = append(x.states, s)
+			open = append(open, s)
+		case strings.HasSuffix(e.Type, "StateExited"):
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i].name == e.Details.Name {
+					open[i].exited = e.time
+					open = append(open[:i], open[i+1:]...)
+					break
+				}
+			}
+		case strings.HasSuffix(e.Type, "Failed") || strings.HasSuffix(e.Type, "TimedOut"):
+			if len(open) > 0 {
+				open[len(open)-1].err = sfnError(e)
+			}
+		}
+	}
+	if x.start.IsZero() && len(events) > 0 {
+		x.start = events[0].time
+	}
+	return x
+}
+
+//truncate shortens s to n runes
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func sfnDuration(from, to time.Time) string {
+	if from.IsZero() || to.IsZero() {
+		return "-"
+	}
+	return to.Sub(from).Round(time.Millisecond).String()
+}
+
+func printSfnExecution(x *sfnExecution) {
+	mark, paint := color.GreenString("✔"), fmt.Sprint
+	switch {
+	case x.failed():
+		mark, paint = color.RedString("✘"), color.New(color.FgRed).SprintFunc()
+	case x.status == "RUNNING":
+		mark = color.YellowString("…")
+	}
+	fmt.Printf("%s %s %s %s %s\n", mark, paint(x.arn), paint(x.status), timeutil.FormatTimestamp(x.start.Unix()), sfnDuration(x.start, x.end))
+	if x.input != "" {
+		fmt.Printf("  input %s\n", truncate(x.input, 100))
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range x.states {
+		detail := "input " + truncate(s.input, 60)
+		if s.err != "" {
+			detail = color.RedString(truncate(s.err, 100))
+		}
+		fmt.Fprintf(w, "  +%s\t%s\t%s\t%s\n", sfnDuration(x.start, s.entered), s.name, sfnDuration(s.entered, s.exited), detail)
+	}
+	w.Flush()
+	if x.err != "" {
+		fmt.Printf("  %s\n", color.RedString(x.err))
+	}
+	fmt.Println()
+}
+
+func sfnTimelines() {
+	target := cloudwatch.Target{}
+	sm, err := cloudwatch.FindStateMachine(target, *sfnRef)
+	kingpin.FatalIfError(err, "")
+	group := *sfnGroup
+	if group == "" {
+		group, err = cloudwatch.StateMachineLogGroup(target, sm)
+		kingpin.FatalIfError(err, "use --group")
+	}
+
+	end := time.Now().UTC()
+	q := cloudwatch.Query{Group: group, Start: end.Add(-*sfnSince), End: end}
+	if sm.Execution != "" {
+		q.Pattern = fmt.Sprintf(`{ $.execution_arn = %q }`, sm.Execution)
+	}
+	executions := make(map[string][]*sfnEvent)
+	err = cloudwatch.Events(target, q, func(event *cloudwatchlogs.FilteredLogEvent) bool {
+		e := &sfnEvent{}
+		if json.Unmarshal([]byte(*event.Message), e) != nil || e.Execution == "" {
+			return true
+		}
+		e.time = time.Unix(0, *event.Timestamp*int64(time.Millisecond))
+		if ms, err := strconv.ParseInt(e.Timestamp, 10, 64); err == nil {
+			e.time = time.Unix(0, ms*int64(time.Millisecond))
+		}
+		executions[e.Execution] = append(executions[e.Execution], e)
+		return true
+	})
+	kingpin.FatalIfError(err, "cannot read the events of %s", group)
+	if len(executions) == 0 {
+		fmt.Printf("No executions of %s logged to %s in the last %s.\n", sm.Name, group, *sfnSince)
+		return
+	}
+
+	var timelines []*sfnExecution
+	for arn, events := range executions {
+		timelines = append(timelines, newSfnExecution(arn, events))
+	}
+	sort.Slice(timelines, func(i, j int) bool { return timelines[i].start.Before(timelines[j].start) })
+	failed := 0
+	for _, x := range timelines {
+		printSfnExecution(x)
+		if x.failed() {
+			failed++
+		}
+	}
+	fmt.Printf("%d executions, %s\n", len(timelines), color.RedString("%d failed", failed))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sfnTestEvent(id string, typ string, at time.Time, name string, input string) *sfnEvent {
+	e := &sfnEvent{ID: id, Type: typ, time: at}
+	e.Details.Name = name
+	if input != "" {
+		e.Details.Input = json.RawMessage(input)
+	}
+	return e
+}
+
+func TestSfnText(t *testing.T) {
+	assert.Equal(t, `{"order":42}`, sfnText(json.RawMessage(`"{\"order\":42}"`)))
+	assert.Equal(t, `{"order":42}`, sfnText(json.RawMessage(`{"order":42}`)))
+	assert.Equal(t, "", sfnText(nil))
+}
+
+func TestNewSfnExecution(t *testing.T) {
+	start := time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+	failed := sfnTestEvent("5", "TaskFailed", start.Add(300*time.Millisecond), "", "")
+	failed.Details.Error, failed.Details.Cause = "States.TaskFailed", "timeout"
+	execFailed := sfnTestEvent("6", "ExecutionFailed", start.Add(400*time.Millisecond), "", "")
+	execFailed.Details.Error = "States.TaskFailed"
+	//out of order, with the same timestamp sorted by id
+	x := newSfnExecution("arn:exec", []*sfnEvent{
+		execFailed,
+		sfnTestEvent("3", "PassStateExited", start.Add(100*time.Millisecond), "Validate", ""),
+		sfnTestEvent("4", "TaskStateEntered", start.Add(100*time.Millisecond), "Charge", `"{\"amount\":5}"`),
+		sfnTestEvent("2", "PassStateEntered", start, "Validate", `"{}"`),
+		sfnTestEvent("1", "ExecutionStarted", start, "", `"{\"order\":42}"`),
+		failed,
+	})
+	assert.Equal(t, "FAILED", x.status)
+	assert.True(t, x.failed())
+	assert.Equal(t, start, x.start)
+	assert.Equal(t, start.Add(400*time.Millisecond), x.end)
+	assert.Equal(t, `{"order":42}`, x.input)
+	assert.Equal(t, "States.TaskFailed", x.err)
+	assert.Len(t, x.states, 2)
+	assert.Equal(t, &sfnState{name: "Validate", entered: start, exited: start.Add(100 * time.Millisecond), input: "{}"}, x.states[0])
+	assert.Equal(t, &sfnState{name: "Charge", entered: start.Add(100 * time.Millisecond), input: `{"amount":5}`, err: "States.TaskFailed: timeout"}, x.states[1])
+}
+
+func TestNewSfnExecutionRunning(t *testing.T) {
+	start := time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+	//the start of the execution is before the time window
+	x := newSfnExecution("arn:exec", []*sfnEvent{sfnTestEvent("7", "TaskStateEntered", start, "Charge", "")})
+	assert.Equal(t, "RUNNING", x.status)
+	assert.False(t, x.failed())
+	assert.Equal(t, start, x.start)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	enrich       = tailCommand.Flag("enrich", "Resolve the EC2 instance IDs in the stream names to the instance Name tag, AZ and private IP.").Enum("ec2")
+	tailOutput   = tailCommand.Flag("output", "Output format: text or json(an object per line).").Short('o').Default("text").Enum("text", "json")
+	tailTemplate = tailCommand.Flag("template", "Render the events with a Go template, e.g. '{{.Stream}} {{.Message}}'.").String()
+)
+
+var (
+	eventTemplate    *template.Template
+	instanceResolver *cloudwatch.InstanceResolver
+)
+
+//tailEvent is the event as rendered by --output json and --template
+type tailEvent struct {
+	Timestamp time.Time            `json:"timestamp"`
+	Stream    string               `json:"stream"`
+	EventID   string               `json:"eventId"`
+	Message   string               `json:"message"`
+	Instance  *cloudwatch.Instance `json:"instance,omitempty"`
+	Audit     *auditSummary        `json:"audit,omitempty"`
+	Alarm     *tailAlarm           `json:"alarm,omitempty"`
+}
+
+//tailAlarm is an alarm state change interleaved with the events by --with-alarms
+type tailAlarm struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+//initTailFormat parses the --template and prepares the --enrich resolver
+func initTailFormat() {
+	if *tailTemplate != "" {
+		t, err := template.New("event").Parse(*tailTemplate)
+		kingpin.FatalIfError(err, "invalid template")
+		eventTemplate = t
+	}
+	if *enrich == "ec2" {
+		instanceResolver = cloudwatch.NewInstanceResolver(cloudwatch.Target{})
+	}
+}
+
+func newTailEvent(event *cloudwatchlogs.FilteredLogEvent) *tailEvent {
+	e := &tailEvent{Timestamp: time.Unix(0, *event.Timestamp*int64(time.Millisecond)).UTC(), Message: *event.Message}
+	if event.LogStreamName != nil {
+		e.Stream = *event.LogStreamName
+	}
+	if event.EventId != nil {
+		e.EventID = *event.EventId
+	}
+	if *eksCluster != "" && cloudwatch.EKSComponent(e.Stream) == "audit" {
+		e.Audit = parseAudit(e.Message)
+	}
+	if instanceResolver != nil {
+		if id := cloudwatch.InstanceIDPattern.FindString(e.Stream); id != "" {
+			instance, err := instanceResolver.Resolve(id)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s cannot describe the EC2 instances, enrichment disabled: %s\n", color.YellowString("Warning:"), err)
+				instanceResolver = nil
+			}
+			e.Instance = instance
+		}
+	}
+	return e
+}
+
+//streamLabel renders the stream with the instance ID replaced by its Name tag, AZ and private IP
+func (e *tailEvent) streamLabel() string {
+	if e.Instance == nil {
+		return e.Stream
+	}
+	name := e.Instance.Name
+	if name == "" {
+		name = e.Instance.ID
+	}
+	return strings.Replace(e.Stream, e.Instance.ID, fmt.Sprintf("%s(%s %s)", name, e.Instance.AZ, e.Instance.PrivateIP), 1)
+}
+
+//render renders the event with the --template or as JSON, it returns false for the text output
+func (e *tailEvent) render() (string, bool) {
+	switch {
+	case eventTemplate != nil:
+		var b strings.Builder
+		if err := eventTemplate.Execute(&b, e); err != nil {
+			kingpin.Fatalf("cannot render the template: %s", err)
+		}
+		return b.String(), true
+	case *tailOutput == "json":
+		b, _ := json.Marshal(e)
+		return string(b), true
+	}
+	return "", false
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamLabel(t *testing.T) {
+	e := &tailEvent{Stream: "web/i-0abc1234def567890/app"}
+	assert.Equal(t, "web/i-0abc1234def567890/app", e.streamLabel())
+	e.Instance = &cloudwatch.Instance{ID: "i-0abc1234def567890", Name: "web-1", AZ: "eu-west-1a", PrivateIP: "10.0.1.5"}
+	assert.Equal(t, "web/web-1(eu-west-1a 10.0.1.5)/app", e.streamLabel())
+	e.Instance.Name = ""
+	assert.Equal(t, "web/i-0abc1234def567890(eu-west-1a 10.0.1.5)/app", e.streamLabel())
+}
+
+func TestRenderText(t *testing.T) {
+	_, ok := (&tailEvent{Message: "hello"}).render()
+	assert.False(t, ok)
+}
+
+func TestRenderJSON(t *testing.T) {
+	*tailOutput = "json"
+	defer func() { *tailOutput = "text" }()
+	e := &tailEvent{Timestamp: time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC), Stream: "s", EventID: "1", Message: "hello"}
+	s, ok := e.render()
+	assert.True(t, ok)
+	assert.Equal(t, `{"timestamp":"2018-07-01T10:00:00Z","stream":"s","eventId":"1","message":"hello"}`, s)
+
+	e = &tailEvent{Timestamp: e.Timestamp, Message: "threshold crossed", Alarm: &tailAlarm{"cw-api", "OK", "ALARM"}}
+	s, _ = e.render()
+	assert.Equal(t, `{"timestamp":"2018-07-01T10:00:00Z","stream":"","eventId":"","message":"threshold crossed","alarm":{"name":"cw-api","from":"OK","to":"ALARM"}}`, s)
+}
+
+func TestRenderTemplate(t *testing.T) {
+	*tailTemplate = `{{if .Alarm}}{{.Alarm.Name}} {{.Alarm.To}}{{else}}{{.Stream}} {{.Message}}{{end}}`
+	initTailFormat()
+	defer func() { *tailTemplate, eventTemplate = "", nil }()
+	s, _ := (&tailEvent{Stream: "s", Message: "hello"}).render()
+	assert.Equal(t, "s hello", s)
+	s, _ = (&tailEvent{Alarm: &tailAlarm{"cw-api", "OK", "ALARM"}}).render()
+	assert.Equal(t, "cw-api ALARM", s)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+)
+
+//groupNode is a node of the log groups tree, aggregating all the groups below it
+type groupNode struct {
+	name        string
+	isGroup     bool
+	groups      int
+	storedBytes int64
+	retentions  map[int64]int
+	children    map[string]*groupNode
+}
+
+func newGroupNode(name string) *groupNode {
+	return &groupNode{name: name, retentions: make(map[int64]int), children: make(map[string]*groupNode)}
+}
+
+//add aggregates the group in the node and in the nodes along its path, up to depth levels below (0 means unlimited)
+func (n *groupNode) add(g *cloudwatch.LogGroup, path []string, depth int) {
+	n.groups++
+	n.storedBytes += g.StoredBytes
+	n.retentions[g.RetentionInDays]++
+	if len(path) == 0 {
+		n.isGroup = true
+		return
+	}
+	if depth == 1 {
+		return
+	}
+	child, ok := n.children[path[0]]
+	if !ok {
+		child = newGroupNode(path[0])
+		n.children[path[0]] = child
+	}
+	child.add(g, path[1:], depth-1)
+}
+
+func (n *groupNode) summary() string {
+	var days []int64
+	for d := range n.retentions {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+	var retentions []string
+	for _, d := range days {
+		r := formatRetention(d)
+		if d == 0 {
+			r = color.RedString(r)
+		}
+		retentions = append(retentions, fmt.Sprintf("%s×%d", r, n.retentions[d]))
+	}
+	groups := "groups"
+	if n.groups == 1 {
+		groups = "group"
+	}
+	return fmt.Sprintf("%d %s, %s, retention: %s", n.groups, groups, formatBytes(n.storedBytes), strings.Join(retentions, " "))
+}
+
+func (n *groupNode) print(indent string) {
+	var names []string
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		child := n.children[name]
+		branch, nextIndent := "├── ", indent+"│   "
+		if i == len(names)-1 {
+			branch, nextIndent = "└── ", indent+"    "
+		}
+		label := child.name
+		if child.isGroup {
+			label = color.GreenString(label)
+		}
+		fmt.Printf("%s%s%s %s\n", indent, branch, label, color.New(color.Faint).Sprintf("(%s)", child.summary()))
+		child.print(nextIndent)
+	}
+}
+
+//buildGroupsTree builds the tree of the log groups split on '/'
+//Levels deeper than depth are collapsed into their ancestor(0 means unlimited)
+func buildGroupsTree(groups []*cloudwatch.LogGroup, depth int) *groupNode {
+	root := newGroupNode("")
+	for _, g := range groups {
+		path := strings.Split(g.Name, "/")
+		if path[0] == "" && len(path) > 1 {
+			path = append([]string{"/" + path[1]}, path[2:]...)
+		}
+		if depth > 0 {
+			root.add(g, path, depth+1)
+		} else {
+			root.add(g, path, 0)
+		}
+	}
+	return root
+}
+
+//printGroupsTree prints the log groups as a tree, see buildGroupsTree
+func printGroupsTree(groups []*cloudwatch.LogGroup, depth int) {
+	root := buildGroupsTree(groups, depth)
+	fmt.Println(root.summary())
+	root.print("")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"github.com/stretchr/testify/assert"
+)
+
+func treeGroups() []*cloudwatch.LogGroup {
+	return []*cloudwatch.LogGroup{
+		{Name: "/ecs/api", StoredBytes: 100, RetentionInDays: 30},
+		{Name: "/ecs/api/access", StoredBytes: 50, RetentionInDays: 7},
+		{Name: "/ecs/web", StoredBytes: 200, RetentionInDays: 30},
+		{Name: "/aws/lambda/orders", StoredBytes: 10},
+		{Name: "legacy", StoredBytes: 1, RetentionInDays: 1},
+	}
+}
+
+func TestBuildGroupsTree(t *testing.T) {
+	root := buildGroupsTree(treeGroups(), 0)
+	assert.Equal(t, 5, root.groups)
+	assert.Equal(t, int64(361), root.storedBytes)
+	assert.Equal(t, map[int64]int{0: 1, 1: 1, 7: 1, 30: 2}, root.retentions)
+	assert.Len(t, root.children, 3)
+
+	ecs := root.children["/ecs"]
+	assert.False(t, ecs.isGroup)
+	assert.Equal(t, 3, ecs.groups)
+	assert.Equal(t, int64(350), ecs.storedBytes)
+	assert.Equal(t, map[int64]int{7: 1, 30: 2}, ecs.retentions)
+
+	api := ecs.children["api"]
+	assert.True(t, api.isGroup)
+	assert.Equal(t, 2, api.groups)
+	assert.Equal(t, int64(150), api.storedBytes)
+	assert.True(t, api.children["access"].isGroup)
+	assert.Empty(t, api.children["access"].children)
+
+	lambda := root.children["/aws"].children["lambda"]
+	assert.False(t, lambda.isGroup)
+	assert.Equal(t, map[int64]int{0: 1}, lambda.children["orders"].retentions)
+
+	assert.True(t, root.children["legacy"].isGroup)
+}
+
+func TestBuildGroupsTreeDepth(t *testing.T) {
+	root := buildGroupsTree(treeGroups(), 1)
+	assert.Equal(t, 5, root.groups)
+	ecs := root.children["/ecs"]
+	assert.Empty(t, ecs.children)
+	assert.Equal(t, 3, ecs.groups)
+	assert.Equal(t, int64(350), ecs.storedBytes)
+	assert.Empty(t, root.children["/aws"].children)
+	assert.True(t, root.children["legacy"].isGroup)
+
+	root = buildGroupsTree(treeGroups(), 2)
+	api := root.children["/ecs"].children["api"]
+	assert.Empty(t, api.children)
+	assert.Equal(t, 2, api.groups)
+	assert.Equal(t, map[int64]int{7: 1, 30: 1}, api.retentions)
+	assert.Empty(t, root.children["/aws"].children["lambda"].children)
+	assert.Equal(t, 1, root.children["/aws"].children["lambda"].groups)
+}
+
+func TestGroupNodeSummary(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	root := buildGroupsTree(treeGroups()[:3], 0)
+	assert.Equal(t, "3 groups, "+formatBytes(350)+", retention: 7d×1 30d×2", root.summary())
+	assert.Equal(t, "1 group, "+formatBytes(50)+", retention: 7d×1", root.children["/ecs"].children["api"].children["access"].summary())
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/lucagrulla/cw/cloudwatch"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	usageCommand = kingpin.Command("usage", "Show the storage usage and estimated cost of the log groups.")
+	usagePattern = usageCommand.Flag("pattern", "Only report the log groups matching the pattern, e.g. '/ecs/*'.").Default("*").String()
+	usageBy      = usageCommand.Flag("by", "Aggregate by log group or by path prefix.").Default("group").Enum("group", "prefix")
+	usageDepth   = usageCommand.Flag("depth", "Number of path segments making a prefix when aggregating by prefix.").Default("2").Int()
+	usageSample  = usageCommand.Flag("sample", "Window of recent events sampled to estimate the ingestion rate. 0 disables sampling.").Default("5m").Duration()
+	usagePrices  = usageCommand.Flag("prices", "JSON price table in USD by region, e.g. {\"eu-west-1\": {\"storage\": 0.03, \"ingestion\": 0.57}}.").ExistingFile()
+	usageOutput  = usageCommand.Flag("output", "Output format.").Short('o').Default("table").Enum("table", "csv", "json")
+)
+
+//maxSampledEvents caps the events read per group when sampling the ingestion rate
+const maxSampledEvents = 10000
+
+const gb = 1 << 30
+
+//price is the USD cost of storing a GB for a month and ingesting a GB
+type price struct {
+	Storage   float64 `json:"storage"`
+	Ingestion float64 `json:"ingestion"`
+}
+
+var defaultPrices = map[string]price{
+	"default":        {Storage: 0.03, Ingestion: 0.50},
+	"us-east-1":      {Storage: 0.03, Ingestion: 0.50},
+	"us-east-2":      {Storage: 0.03, Ingestion: 0.50},
+	"us-west-2":      {Storage: 0.03, Ingestion: 0.50},
+	"eu-west-1":      {Storage: 0.03, Ingestion: 0.57},
+	"eu-west-2":      {Storage: 0.0315, Ingestion: 0.57},
+	"eu-central-1":   {Storage: 0.0324, Ingestion: 0.63},
+	"ap-southeast-1": {Storage: 0.033, Ingestion: 0.67},
+	"ap-southeast-2": {Storage: 0.033, Ingestion: 0.67},
+	"ap-northeast-1": {Storage: 0.033, Ingestion: 0.76},
+}
+
+func regionPrice(region string, file string) (price, error) {
+	prices := defaultPrices
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return price{}, err
+		}
+		defer f.Close()
+		prices = make(map[string]price)
+		if err := json.NewDecoder(f).Decode(&prices); err != nil {
+			return price{}, fmt.Errorf("invalid price table %s: %s", file, err)
+		}
+	}
+	if p, ok := prices[region]; ok {
+		return p, nil
+	}
+	if p, ok := prices["default"]; ok {
+		return p, nil
+	}
+	return price{}, fmt.Errorf("no price for region %s and no default price", region)
+}
+
+type usageEntry struct {
+	Name                 string  `json:"name"`
+	Groups               int     `json:"groups"`
+	StoredBytes          int64   `json:"storedBytes"`
+	NoRetention          int     `json:"noRetention"`
+	IngestionBytesSec    float64 `json:"ingestionBytesPerSecond"`
+	StorageMonthlyCost   float64 `json:"storageMonthlyCost"`
+	IngestionMonthlyCost float64 `json:"ingestionMonthlyCost"`
+}
+
+func (u *usageEntry) cost() float64 {
+	return u.StorageMonthlyCost + u.IngestionMonthlyCost
+}
+
+//groupPrefix returns the first depth path segments of a group name, at least one
+func groupPrefix(name string, depth int) string {
+	if depth < 1 {
+		depth = 1
+	}
+	leading := strings.HasPrefix(name, "/")
+	segments := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	if len(segments) > depth {
+		segments = segments[:depth]
+	}
+	prefix := strings.Join(segments, "/")
+	if leading {
+		prefix = "/" + prefix
+	}
+	return prefix
+}
+
+func sampleIngestion(target cloudwatch.Target, groups []*cloudwatch.LogGroup, window time.Duration) map[string]float64 {
+	rates := make(map[string]float64)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 2) //FilterLogEvents accepts 5 reqs/sec
+	for _, g := range groups {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			sample, err := cloudwatch.SampleIngestion(target, name, window, maxSampledEvents)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "cannot sample %s: %s\n", name, err)
+				return
+			}
+			mu.Lock()
+			rates[name] = sample.BytesPerSecond()
+			mu.Unlock()
+		}(g.Name)
+	}
+	wg.Wait()
+	return rates
+}
+
+func usage() {
+	if *usageDepth < 1 {
+		kingpin.Fatalf("--depth must be at least 1")
+	}
+	target := cloudwatch.Target{}
+	groups, err := cloudwatch.DescribeGroups(target, *usagePattern)
+	kingpin.FatalIfError(err, "cannot describe log groups")
+	region, err := cloudwatch.ResolveRegion(target)
+	kingpin.FatalIfError(err, "")
+	p, err := regionPrice(region, *usagePrices)
+	kingpin.FatalIfError(err, "")
+
+	var rates map[string]float64
+	if *usageSample > 0 {
+		rates = sampleIngestion(target, groups, *usageSample)
+	}
+
+	entries := make(map[string]*usageEntry)
+	for _, g := range groups {
+		name := g.Name
+		if *usageBy == "prefix" {
+			name = groupPrefix(g.Name, *usageDepth)
+		}
+		e, ok := entries[name]
+		if !ok {
+			e = &usageEntry{Name: name}
+			entries[name] = e
+		}
+		e.Groups++
+		e.StoredBytes += g.StoredBytes
+		if g.RetentionInDays == 0 {
+			e.NoRetention++
+		}
+		e.IngestionBytesSec += rates[g.Name]
+	}
+
+	var sorted []*usageEntry
+	for _, e := range entries {
+		e.StorageMonthlyCost = float64(e.StoredBytes) / gb * p.Storage
+		e.IngestionMonthlyCost = e.IngestionBytesSec * (30 * 24 * time.Hour).Seconds() / gb * p.Ingestion
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].cost() != sorted[j].cost() {
+			return sorted[i].cost() > sorted[j].cost()
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	if *usageOutput == "json" {
+		if sorted == nil {
+			sorted = []*usageEntry{}
+		}
+		printJSON(sorted)
+		return
+	}
+
+	header := []string{strings.ToUpper(*usageBy), "GROUPS", "STORED", "NO RETENTION", "INGESTION/S", "STORAGE $/MONTH", "INGESTION $/MONTH"}
+	var rows [][]string
+	for _, e := range sorted {
+		if *usageOutput == "csv" {
+			rows = append(rows, []string{e.Name, strconv.Itoa(e.Groups), strconv.FormatInt(e.StoredBytes, 10), strconv.Itoa(e.NoRetention),
+				fmt.Sprintf("%.0f", e.IngestionBytesSec), fmt.Sprintf("%.2f", e.StorageMonthlyCost), fmt.Sprintf("%.2f", e.IngestionMonthlyCost)})
+			continue
+		}
+		noRetention := strconv.Itoa(e.NoRetention)
+		if e.NoRetention > 0 {
+			noRetention = color.RedString(noRetention)
+		}
+		rows = append(rows, []string{e.Name, strconv.Itoa(e.Groups), formatBytes(e.StoredBytes), noRetention,
+			formatBytes(int64(e.IngestionBytesSec)), fmt.Sprintf("%.2f", e.StorageMonthlyCost), fmt.Sprintf("%.2f", e.IngestionMonthlyCost)})
+	}
+	if *usageOutput == "csv" {
+		printCSV(header, rows)
+		return
+	}
+	printTable(header, rows)
+}
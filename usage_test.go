@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupPrefix(t *testing.T) {
+	assert.Equal(t, "/ecs/prod", groupPrefix("/ecs/prod/api", 2))
+	assert.Equal(t, "/ecs", groupPrefix("/ecs", 2))
+	assert.Equal(t, "lambda/x", groupPrefix("lambda/x/y", 2))
+}
+
+func TestGroupPrefixDepth(t *testing.T) {
+	assert.Equal(t, "/ecs", groupPrefix("/ecs/prod/api", 1))
+	assert.Equal(t, "/ecs", groupPrefix("/ecs/prod/api", 0))
+	assert.Equal(t, "lambda", groupPrefix("lambda/x/y", -1))
+}
@@ -0,0 +1,95 @@
+package yamlutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNesting(t *testing.T) {
+	v, err := Parse(`
+rules:
+  - name: prod-encryption
+    severity: error
+    require-kms: true
+  - name: retention
+    limits:
+      max: 30
+      min: 1
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"name": "prod-encryption", "severity": "error", "require-kms": "true"},
+			map[string]interface{}{"name": "retention", "limits": map[string]interface{}{"max": "30", "min": "1"}},
+		},
+	}, v)
+}
+
+func TestParseLists(t *testing.T) {
+	v, err := Parse(`
+block:
+- a
+- b
+flow: [a, "b, c", 'd']
+empty: []
+nested:
+  -
+    - x
+    - y
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"block":  []interface{}{"a", "b"},
+		"flow":   []interface{}{"a", "b, c", "d"},
+		"empty":  []interface{}{},
+		"nested": []interface{}{[]interface{}{"x", "y"}},
+	}, v)
+}
+
+func TestParseQuoting(t *testing.T) {
+	v, err := Parse(`
+double: "a \"quoted\" # value"
+single: 'it''s'
+"quoted key": plain value
+nothing: ~
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"double":     `a "quoted" # value`,
+		"single":     "it's",
+		"quoted key": "plain value",
+		"nothing":    nil,
+	}, v)
+}
+
+func TestParseComments(t *testing.T) {
+	v, err := Parse(`# rules file
+---
+match: /prod/* # all prod
+url: http://host/a#fragment
+	`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"match": "/prod/*", "url": "http://host/a#fragment"}, v)
+
+	v, err = Parse("# only comments\n\n")
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestParseMalformed(t *testing.T) {
+	for doc, msg := range map[string]string{
+		"a: 1\na: 2":           "line 2: duplicate key \"a\"",
+		"a: 1\njust text":      "line 2: expected a key: value pair",
+		"a:\n    b: 1\n  c: 2": "line 3: unexpected indentation",
+		"a: [1, 2":             "line 1: unterminated flow sequence [1, 2",
+		"a: 'open":             "line 1: unterminated string 'open",
+		"a: \"open":            "line 1: invalid string \"open",
+		"list:\n  - a\n  b: 1": "line 3: unexpected indentation",
+	} {
+		_, err := Parse(doc)
+		if assert.Error(t, err, doc) {
+			assert.Equal(t, msg, err.Error(), doc)
+		}
+	}
+}